@@ -17,20 +17,30 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	MetricsPolicyMax = "Max"
+	MetricsPolicyMin = "Min"
+	MetricsPolicySum = "Sum"
+)
+
 // HorizontalRunnerAutoscalerSpec defines the desired state of HorizontalRunnerAutoscaler
 type HorizontalRunnerAutoscalerSpec struct {
 	// ScaleTargetRef sis the reference to scaled resource like RunnerDeployment
 	ScaleTargetRef ScaleTargetRef `json:"scaleTargetRef,omitempty"`
 
-	// MinReplicas is the minimum number of replicas the deployment is allowed to scale
+	// MinReplicas is the minimum number of replicas the deployment is allowed to scale.
+	// Set it to 0 to let the deployment scale to zero while there's no workflow job to run.
 	// +optional
+	// +kubebuilder:validation:Minimum=0
 	MinReplicas *int `json:"minReplicas,omitempty"`
 
 	// MinReplicas is the maximum number of replicas the deployment is allowed to scale
 	// +optional
+	// +kubebuilder:validation:Minimum=0
 	MaxReplicas *int `json:"maxReplicas,omitempty"`
 
 	// ScaleDownDelaySecondsAfterScaleUp is the approximate delay for a scale down followed by a scale up
@@ -42,6 +52,13 @@ type HorizontalRunnerAutoscalerSpec struct {
 	// +optional
 	Metrics []MetricSpec `json:"metrics,omitempty"`
 
+	// MetricsPolicy is how the desired numbers of runners calculated for each of Metrics are combined into one.
+	// Max uses the largest number, Min the smallest, and Sum the sum of all the numbers capped at MaxReplicas.
+	// Defaults to Max, which is what the Kubernetes HorizontalPodAutoscaler does.
+	// +optional
+	// +kubebuilder:validation:Enum=Max;Min;Sum
+	MetricsPolicy string `json:"metricsPolicy,omitempty"`
+
 	// ScaleUpTriggers is an experimental feature to increase the desired replicas by 1
 	// on each webhook requested received by the webhookBasedAutoscaler.
 	//
@@ -54,6 +71,41 @@ type HorizontalRunnerAutoscalerSpec struct {
 	ScaleUpTriggers []ScaleUpTrigger `json:"scaleUpTriggers,omitempty"`
 
 	CapacityReservations []CapacityReservation `json:"capacityReservations,omitempty" patchStrategy:"merge" patchMergeKey:"name"`
+
+	// ScheduledOverrides is the list of recurring windows during which MinReplicas and MaxReplicas are overridden.
+	// When two or more windows are active at the same time, the one that comes first in the list wins.
+	// +optional
+	ScheduledOverrides []ScheduledOverride `json:"scheduledOverrides,omitempty"`
+
+	// Paused stops the autoscaler from changing the replicas of the deployment, for example to freeze the runners during incidents.
+	// The desired replicas and metrics are still computed and reported in the status.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+}
+
+// ScheduledOverride overrides MinReplicas and MaxReplicas of the autoscaler during a recurring window.
+type ScheduledOverride struct {
+	// Schedule is a cron expression in the standard 5-field format like `0 8 * * 1-5`,
+	// which denotes the times at which the window starts.
+	Schedule string `json:"schedule"`
+
+	// Duration is how long the window lasts from each of the start times, like `11h`.
+	Duration metav1.Duration `json:"duration"`
+
+	// TimeZone is the IANA time zone name like `Europe/Berlin` in which the schedule is interpreted.
+	// Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// MinReplicas is the minimum number of replicas while the window is active
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MinReplicas *int `json:"minReplicas,omitempty"`
+
+	// MaxReplicas is the maximum number of replicas while the window is active
+	// +optional
+	// +kubebuilder:validation:Minimum=0
+	MaxReplicas *int `json:"maxReplicas,omitempty"`
 }
 
 type ScaleUpTrigger struct {
@@ -66,6 +118,7 @@ type GitHubEventScaleUpTriggerSpec struct {
 	CheckRun    *CheckRunSpec    `json:"checkRun,omitempty"`
 	PullRequest *PullRequestSpec `json:"pullRequest,omitempty"`
 	Push        *PushSpec        `json:"push,omitempty"`
+	WorkflowJob *WorkflowJobSpec `json:"workflowJob,omitempty"`
 }
 
 // https://docs.github.com/en/actions/reference/events-that-trigger-workflows#check_run
@@ -91,6 +144,13 @@ type PullRequestSpec struct {
 type PushSpec struct {
 }
 
+// WorkflowJobSpec is the condition for triggering scale-up and scale-down on workflow_job event.
+// A `queued` workflow_job event adds a capacity reservation, and a `completed` one removes a matching reservation
+// right away, so that the number of replicas follows the number of jobs waiting for or running on the runners.
+// Also see https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#workflow_job
+type WorkflowJobSpec struct {
+}
+
 // CapacityReservation specifies the number of replicas temporarily added
 // to the scale target until ExpirationTime.
 type CapacityReservation struct {
@@ -113,6 +173,13 @@ type MetricSpec struct {
 	// +optional
 	RepositoryNames []string `json:"repositoryNames,omitempty"`
 
+	// RepositoryNamePatterns is the list of glob patterns like `*` or `service-*` to find repositories of
+	// the organization to be used for calculating the metric, in addition to RepositoryNames.
+	// A pattern prefixed with `!` excludes matching repositories.
+	// Repositories are looked up on every sync so that newly created ones are picked up without updating the autoscaler.
+	// +optional
+	RepositoryNamePatterns []string `json:"repositoryNamePatterns,omitempty"`
+
 	// ScaleUpThreshold is the percentage of busy runners greater than which will
 	// trigger the hpa to scale runners up.
 	// +optional
@@ -160,6 +227,70 @@ type HorizontalRunnerAutoscalerStatus struct {
 
 	// +optional
 	CacheEntries []CacheEntry `json:"cacheEntries,omitempty"`
+
+	// CurrentMetrics is the number of replicas desired by each of the metrics, as of the last time they were calculated.
+	// +optional
+	CurrentMetrics []MetricStatus `json:"currentMetrics,omitempty"`
+
+	// LastScaleTime is the last time the autoscaler changed the number of replicas of the scale target.
+	// +optional
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+
+	// LastScaleReason explains why the autoscaler changed the number of replicas the last time.
+	// +optional
+	LastScaleReason string `json:"lastScaleReason,omitempty"`
+
+	// Conditions is the set of conditions describing the current state of the autoscaler.
+	// +optional
+	Conditions []HorizontalRunnerAutoscalerCondition `json:"conditions,omitempty"`
+}
+
+// MetricStatus is the result of calculating the desired number of replicas for a metric.
+type MetricStatus struct {
+	// Type is the type of the metric, like TotalNumberOfQueuedAndInProgressWorkflowRuns
+	Type string `json:"type,omitempty"`
+
+	// CurrentValue is the value of the metric observed the last time, which is the number of queued and in-progress
+	// workflow jobs for TotalNumberOfQueuedAndInProgressWorkflowRuns, and the fraction of busy runners like `0.75`
+	// for PercentageRunnersBusy.
+	// +optional
+	CurrentValue string `json:"currentValue,omitempty"`
+
+	// DesiredReplicas is the number of replicas desired by the metric, within MinReplicas and MaxReplicas
+	DesiredReplicas int `json:"desiredReplicas"`
+}
+
+// HorizontalRunnerAutoscalerConditionType is the type of a condition of HorizontalRunnerAutoscaler.
+type HorizontalRunnerAutoscalerConditionType string
+
+const (
+	// AbleToScale tells if the autoscaler is able to change the number of replicas of the scale target,
+	// and if it's currently holding back a scale down.
+	AbleToScale HorizontalRunnerAutoscalerConditionType = "AbleToScale"
+
+	// ScalingActive tells if the autoscaler is able to compute the desired number of replicas.
+	ScalingActive HorizontalRunnerAutoscalerConditionType = "ScalingActive"
+
+	// ScalingLimited tells if the desired number of replicas is bounded by MinReplicas or MaxReplicas.
+	ScalingLimited HorizontalRunnerAutoscalerConditionType = "ScalingLimited"
+)
+
+// HorizontalRunnerAutoscalerCondition describes the state of a HorizontalRunnerAutoscaler at a certain point.
+type HorizontalRunnerAutoscalerCondition struct {
+	Type   HorizontalRunnerAutoscalerConditionType `json:"type"`
+	Status corev1.ConditionStatus                  `json:"status"`
+
+	// LastTransitionTime is the last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is the machine-readable reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is the human-readable explanation of the condition.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 const CacheEntryKeyDesiredReplicas = "desiredReplicas"
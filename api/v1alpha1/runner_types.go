@@ -18,11 +18,30 @@ package v1alpha1
 
 import (
 	"errors"
+	"time"
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// Defaults of the timeouts and the interval of RunnerSpec
+	DefaultRegistrationTimeout       = 10 * time.Minute
+	DefaultRegistrationCheckInterval = time.Minute
+	DefaultPodDeletionTimeout        = time.Minute
+	DefaultMaxRegistrationFailures   = 5
+
+	// RunnerPhaseDraining is the phase of the deleted runner waiting for its running job to complete.
+	RunnerPhaseDraining = "Draining"
+
+	// RegistrationTokenSecretKey is the key of the registration token in the secret of the runner.
+	RegistrationTokenSecretKey = "token"
+
+	// AnnotationKeyRegistrationTokenExpiresAt is the annotation of the secret of the runner
+	// that stores the expiration time of the registration token in RFC3339.
+	AnnotationKeyRegistrationTokenExpiresAt = "actions.summerwind.dev/registration-token-expires-at"
+)
+
 // RunnerSpec defines the desired state of Runner
 type RunnerSpec struct {
 	// +optional
@@ -43,6 +62,48 @@ type RunnerSpec struct {
 	// +optional
 	Group string `json:"group,omitempty"`
 
+	// GitHubAPICredentialsFrom makes the controller manage the runner with the GitHub API credentials stored in the secret,
+	// instead of the ones given to the controller.
+	// Use it to run runners for organizations with their own GitHub App installations, or on another GitHub Enterprise Server.
+	// +optional
+	GitHubAPICredentialsFrom *GitHubAPICredentialsFrom `json:"githubAPICredentialsFrom,omitempty"`
+
+	// Ephemeral makes the runner register itself with the `--ephemeral` flag so that it runs at most one job.
+	// Once the runner pod completes, the Runner is deleted instead of getting its pod restarted, and
+	// the owning RunnerReplicaSet creates a fresh Runner in its place.
+	// That way no workspace, docker cache or credential is ever reused between two jobs.
+	// +optional
+	Ephemeral *bool `json:"ephemeral,omitempty"`
+
+	// MaxDrainDuration is how long the deleted runner waits for its running job to complete.
+	// Once it passes, the runner pod is deleted to cancel the job, and the runner is removed from GitHub.
+	// The runner waits for the job to complete without limit when omitted.
+	// +optional
+	MaxDrainDuration *metav1.Duration `json:"maxDrainDuration,omitempty"`
+
+	// RegistrationTimeout is how long the runner is given to register itself to GitHub after its pod is created.
+	// Once it passes, the runner pod is recreated, and the runner is chosen first on scale down.
+	// Defaults to 10m.
+	// +optional
+	RegistrationTimeout *metav1.Duration `json:"registrationTimeout,omitempty"`
+
+	// RegistrationCheckInterval is the minimum interval of checking whether the runner is registered to GitHub and busy.
+	// Defaults to 1m.
+	// +optional
+	RegistrationCheckInterval *metav1.Duration `json:"registrationCheckInterval,omitempty"`
+
+	// PodDeletionTimeout is how long the controller waits for the runner pod to be deleted,
+	// before it forcefully deletes the pod stuck on e.g. an unreachable node.
+	// Defaults to 1m.
+	// +optional
+	PodDeletionTimeout *metav1.Duration `json:"podDeletionTimeout,omitempty"`
+
+	// MaxRegistrationFailures is the number of times the runner pod is recreated after failing to register itself to GitHub,
+	// before the runner gives up in the Failed phase.
+	// Defaults to 5.
+	// +optional
+	MaxRegistrationFailures *int32 `json:"maxRegistrationFailures,omitempty"`
+
 	// +optional
 	Containers []corev1.Container `json:"containers,omitempty"`
 	// +optional
@@ -96,6 +157,20 @@ type RunnerSpec struct {
 	DockerMTU *int64 `json:"dockerMTU,omitempty"`
 }
 
+// GitHubAPICredentialsFrom references the GitHub API credentials to manage runners with.
+type GitHubAPICredentialsFrom struct {
+	// SecretRef is the reference to the secret in the namespace of the runner.
+	// The secret has either `github_token`, or `github_app_id`, `github_app_private_key` and optionally `github_app_installation_id`,
+	// in the same way as the secret of the controller.
+	// It may also have `github_enterprise_url` to manage runners on GitHub Enterprise Server.
+	SecretRef SecretReference `json:"secretRef"`
+}
+
+// SecretReference references a secret in the same namespace.
+type SecretReference struct {
+	Name string `json:"name"`
+}
+
 // ValidateRepository validates repository field.
 func (rs *RunnerSpec) ValidateRepository() error {
 	// Enterprise, Organization and repository are both exclusive.
@@ -119,6 +194,43 @@ func (rs *RunnerSpec) ValidateRepository() error {
 	return nil
 }
 
+// IsEphemeral returns true when the runner is meant to run only one job.
+func (rs *RunnerSpec) IsEphemeral() bool {
+	return rs.Ephemeral != nil && *rs.Ephemeral
+}
+
+// GetRegistrationTimeout returns RegistrationTimeout, or its default when it's omitted.
+func (rs *RunnerSpec) GetRegistrationTimeout() time.Duration {
+	return durationOrDefault(rs.RegistrationTimeout, DefaultRegistrationTimeout)
+}
+
+// GetRegistrationCheckInterval returns RegistrationCheckInterval, or its default when it's omitted.
+func (rs *RunnerSpec) GetRegistrationCheckInterval() time.Duration {
+	return durationOrDefault(rs.RegistrationCheckInterval, DefaultRegistrationCheckInterval)
+}
+
+// GetPodDeletionTimeout returns PodDeletionTimeout, or its default when it's omitted.
+func (rs *RunnerSpec) GetPodDeletionTimeout() time.Duration {
+	return durationOrDefault(rs.PodDeletionTimeout, DefaultPodDeletionTimeout)
+}
+
+// GetMaxRegistrationFailures returns MaxRegistrationFailures, or its default when it's omitted.
+func (rs *RunnerSpec) GetMaxRegistrationFailures() int32 {
+	if rs.MaxRegistrationFailures == nil || *rs.MaxRegistrationFailures <= 0 {
+		return DefaultMaxRegistrationFailures
+	}
+
+	return *rs.MaxRegistrationFailures
+}
+
+func durationOrDefault(d *metav1.Duration, def time.Duration) time.Duration {
+	if d == nil || d.Duration <= 0 {
+		return def
+	}
+
+	return d.Duration
+}
+
 // RunnerStatus defines the observed state of Runner
 type RunnerStatus struct {
 	Registration RunnerStatusRegistration `json:"registration"`
@@ -128,16 +240,25 @@ type RunnerStatus struct {
 
 	//+optional
 	LastRegistrationCheckTime *metav1.Time `json:"lastRegistrationCheckTime"`
+
+	// FailureCount is the number of times the runner pod has been recreated after failing to register itself to GitHub,
+	// since the runner last registered. Reason and Message describe the last failure.
+	// +optional
+	FailureCount int32 `json:"failureCount,omitempty"`
+
+	// +optional
+	LastFailureTime *metav1.Time `json:"lastFailureTime,omitempty"`
 }
 
 // RunnerStatusRegistration contains runner registration status
 type RunnerStatusRegistration struct {
-	Enterprise   string      `json:"enterprise,omitempty"`
-	Organization string      `json:"organization,omitempty"`
-	Repository   string      `json:"repository,omitempty"`
-	Labels       []string    `json:"labels,omitempty"`
-	Token        string      `json:"token"`
-	ExpiresAt    metav1.Time `json:"expiresAt"`
+	Enterprise   string   `json:"enterprise,omitempty"`
+	Organization string   `json:"organization,omitempty"`
+	Repository   string   `json:"repository,omitempty"`
+	Labels       []string `json:"labels,omitempty"`
+	// ExpiresAt is the expiration time of the registration token stored in the secret of the runner.
+	// The token itself isn't stored in the status, so that it's readable only by who can read the secret.
+	ExpiresAt metav1.Time `json:"expiresAt"`
 }
 
 // +kubebuilder:object:root=true
@@ -157,17 +278,28 @@ type Runner struct {
 	Status RunnerStatus `json:"status,omitempty"`
 }
 
-func (r Runner) IsRegisterable() bool {
+// RegistrationTokenSecretName returns the name of the secret that stores the registration token of the runner.
+func (r Runner) RegistrationTokenSecretName() string {
+	return r.Name + "-registration-token"
+}
+
+// IsRegisterable returns true when secret, the secret of the runner, has a registration token
+// that is valid for the repository of the runner.
+func (r Runner) IsRegisterable(secret *corev1.Secret) bool {
 	if r.Status.Registration.Repository != r.Spec.Repository {
 		return false
 	}
 
-	if r.Status.Registration.Token == "" {
+	if secret == nil {
+		return false
+	}
+
+	expiresAt, err := time.Parse(time.RFC3339, secret.Annotations[AnnotationKeyRegistrationTokenExpiresAt])
+	if err != nil {
 		return false
 	}
 
-	now := metav1.Now()
-	if r.Status.Registration.ExpiresAt.Before(&now) {
+	if expiresAt.Before(time.Now()) {
 		return false
 	}
 
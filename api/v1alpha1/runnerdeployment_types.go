@@ -17,12 +17,20 @@ limitations under the License.
 package v1alpha1
 
 import (
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
 )
 
 const (
 	AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns = "TotalNumberOfQueuedAndInProgressWorkflowRuns"
 	AutoscalingMetricTypePercentageRunnersBusy                        = "PercentageRunnersBusy"
+
+	// AnnotationKeyRevision is the annotation of a runnerreplicaset that records the revision of the runnerdeployment template
+	// the runnerreplicaset is created for.
+	AnnotationKeyRevision = "actions.summerwind.dev/revision"
+
+	DefaultRevisionHistoryLimit = 10
 )
 
 // RunnerDeploymentSpec defines the desired state of RunnerDeployment
@@ -35,16 +43,127 @@ type RunnerDeploymentSpec struct {
 	// +nullable
 	Selector *metav1.LabelSelector `json:"selector"`
 	Template RunnerTemplate        `json:"template"`
+
+	// Strategy is how the runners are replaced with the ones of the updated template.
+	// +optional
+	Strategy RunnerDeploymentStrategy `json:"strategy,omitempty"`
+
+	// RevisionHistoryLimit is the number of old runnerreplicasets kept at 0 replicas, so that you can roll back to them.
+	// Defaults to 10.
+	// +optional
+	// +nullable
+	RevisionHistoryLimit *int32 `json:"revisionHistoryLimit,omitempty"`
+
+	// RollbackTo replaces the template with the one of an earlier revision.
+	// The controller clears the field once the template is replaced.
+	// +optional
+	// +nullable
+	RollbackTo *RollbackConfig `json:"rollbackTo,omitempty"`
+
+	// Paused stops the controller from creating runnerreplicasets and changing their replicas,
+	// for example to freeze the runners during incidents. Rollbacks are also deferred until the deployment is resumed.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// ScaleDownPolicy is the order in which idle runners are deleted on scale down.
+	// It's applied to the runnerreplicasets in place, without rolling out the runners.
+	// Defaults to UnregisteredFirst.
+	// +optional
+	// +kubebuilder:validation:Enum=NewestFirst;OldestFirst;UnregisteredFirst;EmptiestNodeFirst
+	ScaleDownPolicy string `json:"scaleDownPolicy,omitempty"`
+}
+
+type RollbackConfig struct {
+	// Revision is the revision to roll back to. 0 means the revision just before the current one.
+	// +optional
+	Revision int64 `json:"revision,omitempty"`
+}
+
+func (s RunnerDeploymentSpec) GetRevisionHistoryLimit() int {
+	if s.RevisionHistoryLimit == nil {
+		return DefaultRevisionHistoryLimit
+	}
+
+	return int(*s.RevisionHistoryLimit)
+}
+
+// RunnerDeploymentStrategy replaces the runners step by step, keeping the number of runners within the limits.
+// Old runners are removed only when they are idle, so that no running job is interrupted.
+type RunnerDeploymentStrategy struct {
+	// MaxSurge is the maximum number of runners that can be created over the desired replicas during a rollout.
+	// Value can be an absolute number or a percentage of the desired replicas, rounded up.
+	// Defaults to 25%.
+	// +optional
+	MaxSurge *intstr.IntOrString `json:"maxSurge,omitempty"`
+
+	// MaxUnavailable is the maximum number of runners that can be unavailable under the desired replicas during a rollout.
+	// Value can be an absolute number or a percentage of the desired replicas, rounded down.
+	// It's treated as 1 when both MaxSurge and MaxUnavailable are 0, so that the rollout can make progress.
+	// Defaults to 25%.
+	// +optional
+	MaxUnavailable *intstr.IntOrString `json:"maxUnavailable,omitempty"`
 }
 
 type RunnerDeploymentStatus struct {
+	// AvailableReplicas is the number of runners of all the runnerreplicasets, excluding the ones being deleted.
 	AvailableReplicas int `json:"availableReplicas"`
-	ReadyReplicas     int `json:"readyReplicas"`
+	// ReadyReplicas is the number of runners of all the runnerreplicasets whose pods are running.
+	ReadyReplicas int `json:"readyReplicas"`
 
 	// Replicas is the total number of desired, non-terminated and latest pods to be set for the primary RunnerSet
 	// This doesn't include outdated pods while upgrading the deployment and replacing the runnerset.
 	// +optional
 	Replicas *int `json:"desiredReplicas,omitempty"`
+
+	// UpdatedReplicas is the number of runners created from the current template.
+	// +optional
+	UpdatedReplicas int `json:"updatedReplicas,omitempty"`
+
+	// RegisteredReplicas is the number of runners registered to GitHub and online.
+	// +optional
+	RegisteredReplicas int `json:"registeredReplicas,omitempty"`
+
+	// BusyReplicas is the number of registered runners running jobs.
+	// +optional
+	BusyReplicas int `json:"busyReplicas,omitempty"`
+
+	// ObservedGeneration is the generation of the runnerdeployment the status is computed for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions is the set of conditions describing the current state of the runnerdeployment.
+	// +optional
+	Conditions []RunnerDeploymentCondition `json:"conditions,omitempty"`
+}
+
+// RunnerDeploymentConditionType is the type of a condition of RunnerDeployment and RunnerReplicaSet.
+type RunnerDeploymentConditionType string
+
+const (
+	// RunnerDeploymentAvailable tells if enough runners are registered to GitHub to run jobs.
+	RunnerDeploymentAvailable RunnerDeploymentConditionType = "Available"
+
+	// RunnerDeploymentProgressing tells if the runners are being created, updated or removed as desired,
+	// and is False when runners have given up registering to GitHub.
+	RunnerDeploymentProgressing RunnerDeploymentConditionType = "Progressing"
+)
+
+// RunnerDeploymentCondition describes the state of a RunnerDeployment or RunnerReplicaSet at a certain point.
+type RunnerDeploymentCondition struct {
+	Type   RunnerDeploymentConditionType `json:"type"`
+	Status corev1.ConditionStatus        `json:"status"`
+
+	// LastTransitionTime is the last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+
+	// Reason is the machine-readable reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+
+	// Message is the human-readable explanation of the condition.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
@@ -52,6 +171,9 @@ type RunnerDeploymentStatus struct {
 // +kubebuilder:printcolumn:JSONPath=".spec.replicas",name=Desired,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.availableReplicas",name=Current,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.readyReplicas",name=Ready,type=number
+// +kubebuilder:printcolumn:JSONPath=".status.updatedReplicas",name=Up-To-Date,type=number
+// +kubebuilder:printcolumn:JSONPath=".status.registeredReplicas",name=Registered,type=number
+// +kubebuilder:printcolumn:JSONPath=".status.busyReplicas",name=Busy,type=number
 
 // RunnerDeployment is the Schema for the runnerdeployments API
 type RunnerDeployment struct {
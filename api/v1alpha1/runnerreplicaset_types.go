@@ -20,6 +20,25 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
+const (
+	// ScaleDownPolicyNewestFirst deletes the most recently created runners first.
+	ScaleDownPolicyNewestFirst = "NewestFirst"
+	// ScaleDownPolicyOldestFirst deletes the least recently created runners first.
+	ScaleDownPolicyOldestFirst = "OldestFirst"
+	// ScaleDownPolicyUnregisteredFirst deletes the runners that failed to register to GitHub or are offline first,
+	// then the ones that are yet to register within the registration timeout, and then the most recently created ones.
+	// Unlike the other policies, it deletes the runners that are yet to register, too.
+	ScaleDownPolicyUnregisteredFirst = "UnregisteredFirst"
+	// ScaleDownPolicyEmptiestNodeFirst deletes the runners on the nodes with the fewest runners first,
+	// so that whole nodes are freed for the cluster autoscaler.
+	ScaleDownPolicyEmptiestNodeFirst = "EmptiestNodeFirst"
+
+	// AnnotationKeyDeletionCost is the annotation of a runner to tell the cost of deleting it on scale down, like
+	// controller.kubernetes.io/pod-deletion-cost of pods. Runners with lower costs are deleted first,
+	// regardless of the scale down policy. Defaults to 0.
+	AnnotationKeyDeletionCost = "actions.summerwind.dev/deletion-cost"
+)
+
 // RunnerReplicaSetSpec defines the desired state of RunnerReplicaSet
 type RunnerReplicaSetSpec struct {
 	// +optional
@@ -30,11 +49,35 @@ type RunnerReplicaSetSpec struct {
 	// +nullable
 	Selector *metav1.LabelSelector `json:"selector"`
 	Template RunnerTemplate        `json:"template"`
+
+	// ScaleDownPolicy is the order in which idle runners are deleted on scale down.
+	// Busy runners are never deleted. Defaults to UnregisteredFirst.
+	// +optional
+	// +kubebuilder:validation:Enum=NewestFirst;OldestFirst;UnregisteredFirst;EmptiestNodeFirst
+	ScaleDownPolicy string `json:"scaleDownPolicy,omitempty"`
 }
 
 type RunnerReplicaSetStatus struct {
+	// AvailableReplicas is the number of runners, excluding the ones being deleted.
 	AvailableReplicas int `json:"availableReplicas"`
-	ReadyReplicas     int `json:"readyReplicas"`
+	// ReadyReplicas is the number of runners whose pods are running.
+	ReadyReplicas int `json:"readyReplicas"`
+
+	// RegisteredReplicas is the number of runners registered to GitHub and online.
+	// +optional
+	RegisteredReplicas int `json:"registeredReplicas,omitempty"`
+
+	// BusyReplicas is the number of registered runners running jobs.
+	// +optional
+	BusyReplicas int `json:"busyReplicas,omitempty"`
+
+	// ObservedGeneration is the generation of the runnerreplicaset the status is computed for.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions is the set of conditions describing the current state of the runnerreplicaset.
+	// +optional
+	Conditions []RunnerDeploymentCondition `json:"conditions,omitempty"`
 }
 
 type RunnerTemplate struct {
@@ -48,6 +91,8 @@ type RunnerTemplate struct {
 // +kubebuilder:printcolumn:JSONPath=".spec.replicas",name=Desired,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.availableReplicas",name=Current,type=number
 // +kubebuilder:printcolumn:JSONPath=".status.readyReplicas",name=Ready,type=number
+// +kubebuilder:printcolumn:JSONPath=".status.registeredReplicas",name=Registered,type=number
+// +kubebuilder:printcolumn:JSONPath=".status.busyReplicas",name=Busy,type=number
 
 // RunnerReplicaSet is the Schema for the runnerreplicasets API
 type RunnerReplicaSet struct {
@@ -10,6 +10,9 @@ import (
 	"time"
 
 	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/controllers/metrics"
+	"github.com/summerwind/actions-runner-controller/github"
+	"github.com/summerwind/actions-runner-controller/pkg/actionsglob"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -63,33 +66,108 @@ func (r *HorizontalRunnerAutoscalerReconciler) getDesiredReplicasFromCache(hra v
 	return nil
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) determineDesiredReplicas(rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, error) {
+// determineDesiredReplicas calculates the desired replicas for each of the metrics and combines them according to
+// the metrics policy. It also returns the desired replicas per metric so that they can be exposed in the status.
+func (r *HorizontalRunnerAutoscalerReconciler) determineDesiredReplicas(ghc *github.Client, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, []v1alpha1.MetricStatus, error) {
 	if hra.Spec.MinReplicas == nil {
-		return nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing minReplicas", hra.Namespace, hra.Name)
+		return nil, nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing minReplicas", hra.Namespace, hra.Name)
 	} else if hra.Spec.MaxReplicas == nil {
-		return nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing maxReplicas", hra.Namespace, hra.Name)
+		return nil, nil, fmt.Errorf("horizontalrunnerautoscaler %s/%s is missing maxReplicas", hra.Namespace, hra.Name)
 	}
 
 	metrics := hra.Spec.Metrics
 	if len(metrics) == 0 {
 		if len(hra.Spec.ScaleUpTriggers) == 0 {
-			return r.calculateReplicasByQueuedAndInProgressWorkflowRuns(rd, hra)
+			st, err := r.calculateReplicasByQueuedAndInProgressWorkflowRuns(ghc, rd, hra, nil)
+			if err != nil {
+				return nil, nil, err
+			}
+
+			return &st.DesiredReplicas, nil, nil
 		}
 
-		return hra.Spec.MinReplicas, nil
-	} else if metrics[0].Type == v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns {
-		return r.calculateReplicasByQueuedAndInProgressWorkflowRuns(rd, hra)
-	} else if metrics[0].Type == v1alpha1.AutoscalingMetricTypePercentageRunnersBusy {
-		return r.calculateReplicasByPercentageRunnersBusy(rd, hra)
-	} else {
-		return nil, fmt.Errorf("validting autoscaling metrics: unsupported metric type %q", metrics[0].Type)
+		return hra.Spec.MinReplicas, nil, nil
+	}
+
+	switch hra.Spec.MetricsPolicy {
+	case "", v1alpha1.MetricsPolicyMax, v1alpha1.MetricsPolicyMin, v1alpha1.MetricsPolicySum:
+	default:
+		return nil, nil, fmt.Errorf("validating autoscaling metrics: unsupported metrics policy %q", hra.Spec.MetricsPolicy)
+	}
+
+	var statuses []v1alpha1.MetricStatus
+
+	for i := range metrics {
+		metric := metrics[i]
+
+		var (
+			st  *v1alpha1.MetricStatus
+			err error
+		)
+
+		switch metric.Type {
+		case v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns:
+			st, err = r.calculateReplicasByQueuedAndInProgressWorkflowRuns(ghc, rd, hra, &metric)
+		case v1alpha1.AutoscalingMetricTypePercentageRunnersBusy:
+			st, err = r.calculateReplicasByPercentageRunnersBusy(ghc, rd, hra, metric)
+		default:
+			err = fmt.Errorf("validting autoscaling metrics: unsupported metric type %q", metric.Type)
+		}
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		statuses = append(statuses, *st)
 	}
+
+	desiredReplicas := combineDesiredReplicas(hra.Spec.MetricsPolicy, statuses, *hra.Spec.MaxReplicas)
+
+	r.Log.V(1).Info(
+		"Combined desired replicas of all the metrics",
+		"metrics_policy", hra.Spec.MetricsPolicy,
+		"metrics", statuses,
+		"replicas_desired", desiredReplicas,
+		"namespace", hra.Namespace,
+		"runner_deployment", rd.Name,
+		"horizontal_runner_autoscaler", hra.Name,
+	)
+
+	return &desiredReplicas, statuses, nil
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByQueuedAndInProgressWorkflowRuns(rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, error) {
+// combineDesiredReplicas combines the non-empty list of desired replicas per metric into one, according to the policy.
+// The empty policy is treated as Max.
+func combineDesiredReplicas(policy string, statuses []v1alpha1.MetricStatus, maxReplicas int) int {
+	combined := statuses[0].DesiredReplicas
+
+	for _, s := range statuses[1:] {
+		switch policy {
+		case v1alpha1.MetricsPolicyMin:
+			if s.DesiredReplicas < combined {
+				combined = s.DesiredReplicas
+			}
+		case v1alpha1.MetricsPolicySum:
+			combined += s.DesiredReplicas
+		default:
+			if s.DesiredReplicas > combined {
+				combined = s.DesiredReplicas
+			}
+		}
+	}
+
+	if combined > maxReplicas {
+		combined = maxReplicas
+	}
+
+	return combined
+}
+
+// calculateReplicasByQueuedAndInProgressWorkflowRuns calculates the desired replicas by the number of queued and in-progress workflow runs.
+// metric can be nil when the autoscaler has no metrics at all, in which case this is used as the default.
+func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByQueuedAndInProgressWorkflowRuns(ghc *github.Client, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler, metric *v1alpha1.MetricSpec) (*v1alpha1.MetricStatus, error) {
 
 	var repos [][]string
-	metrics := hra.Spec.Metrics
 	repoID := rd.Spec.Template.Spec.Repository
 	if repoID == "" {
 		orgName := rd.Spec.Template.Spec.Organization
@@ -100,15 +178,23 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByQueuedAndInPro
 		// In case it's an organizational runners deployment without any scaling metrics defined,
 		// we assume that the desired replicas should always be `minReplicas + capacityReservedThroughWebhook`.
 		// See https://github.com/summerwind/actions-runner-controller/issues/377#issuecomment-793372693
-		if len(metrics) == 0 {
-			return hra.Spec.MinReplicas, nil
+		if metric == nil {
+			return &v1alpha1.MetricStatus{
+				Type:            v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+				DesiredReplicas: *hra.Spec.MinReplicas,
+			}, nil
 		}
 
-		if len(metrics[0].RepositoryNames) == 0 {
-			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames is required and must have one more more entries for organizational runner deployment")
+		if len(metric.RepositoryNames) == 0 && len(metric.RepositoryNamePatterns) == 0 {
+			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames or repositoryNamePatterns is required and must have one more more entries for organizational runner deployment")
 		}
 
-		for _, repoName := range metrics[0].RepositoryNames {
+		repoNames, err := r.listRepositoryNames(ghc, orgName, *metric)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, repoName := range repoNames {
 			repos = append(repos, []string{orgName, repoName})
 		}
 	} else {
@@ -117,21 +203,30 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByQueuedAndInPro
 		repos = append(repos, repo)
 	}
 
-	var total, inProgress, queued, completed, unknown int
+	runnerLabels := runnerLabelSet(rd.Spec.Template.Spec.Labels, r.CommonRunnerLabels)
+
+	var total, inProgress, queued, completed, unknown, unmatched int
 	type callback func()
 	listWorkflowJobs := func(user string, repoName string, runID int64, fallback_cb callback) {
 		if runID == 0 {
 			fallback_cb()
 			return
 		}
-		jobs, _, err := r.GitHubClient.Actions.ListWorkflowJobs(context.TODO(), user, repoName, runID, nil)
+		jobs, err := ghc.ListWorkflowJobs(context.TODO(), user, repoName, runID)
 		if err != nil {
 			r.Log.Error(err, "Error listing workflow jobs")
 			fallback_cb()
-		} else if len(jobs.Jobs) == 0 {
+		} else if len(jobs) == 0 {
 			fallback_cb()
 		} else {
-			for _, job := range jobs.Jobs {
+			for _, job := range jobs {
+				// A job that can't be run by the runners of this deployment, like the one for GitHub-hosted runners
+				// or another deployment with different labels, shouldn't result in scaling this deployment.
+				if !canRunJob(runnerLabels, job.Labels) {
+					unmatched++
+					continue
+				}
+
 				switch job.GetStatus() {
 				case "completed":
 					// We add a case for `completed` so it is not counted in `unknown`.
@@ -153,7 +248,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByQueuedAndInPro
 
 	for _, repo := range repos {
 		user, repoName := repo[0], repo[1]
-		workflowRuns, err := r.GitHubClient.ListRepositoryWorkflowRuns(context.TODO(), user, repoName)
+		workflowRuns, err := ghc.ListRepositoryWorkflowRuns(context.TODO(), user, repoName)
 		if err != nil {
 			return nil, err
 		}
@@ -193,7 +288,6 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByQueuedAndInPro
 	}
 
 	rd.Status.Replicas = &desiredReplicas
-	replicas := desiredReplicas
 
 	r.Log.V(1).Info(
 		"Calculated desired replicas",
@@ -204,33 +298,135 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByQueuedAndInPro
 		"workflow_runs_in_progress", inProgress,
 		"workflow_runs_queued", queued,
 		"workflow_runs_unknown", unknown,
+		"workflow_jobs_unmatched", unmatched,
 		"namespace", hra.Namespace,
 		"runner_deployment", rd.Name,
 		"horizontal_runner_autoscaler", hra.Name,
 	)
 
-	return &replicas, nil
+	return &v1alpha1.MetricStatus{
+		Type:            v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+		CurrentValue:    strconv.Itoa(necessaryReplicas),
+		DesiredReplicas: desiredReplicas,
+	}, nil
+}
+
+// defaultRunnerLabels is the list of labels every runner registers with on its own.
+// The runner also registers with the labels of its OS and architecture, but we can't know them from the spec,
+// as the runner image and the node it runs on can be anything. Add them to the spec labels to match jobs requesting them.
+// See https://docs.github.com/en/actions/hosting-your-own-runners/using-labels-with-self-hosted-runners
+var defaultRunnerLabels = []string{"self-hosted"}
+
+// runnerLabelSet returns the set of lower-cased labels a runner created from the spec has.
+func runnerLabelSet(specLabels, commonLabels []string) map[string]struct{} {
+	labels := map[string]struct{}{}
+
+	for _, ls := range [][]string{defaultRunnerLabels, specLabels, commonLabels} {
+		for _, l := range ls {
+			labels[strings.ToLower(l)] = struct{}{}
+		}
+	}
+
+	return labels
+}
+
+// canRunJob returns true when the runner has all the labels requested by the job's `runs-on`.
+// Labels are case-insensitive, as they are on GitHub.
+// A job without labels, which happens when GitHub doesn't return them, is assumed to be runnable.
+func canRunJob(runnerLabels map[string]struct{}, jobLabels []string) bool {
+	for _, l := range jobLabels {
+		if _, ok := runnerLabels[strings.ToLower(l)]; !ok {
+			return false
+		}
+	}
+
+	return true
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPercentageRunnersBusy(rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, error) {
+// listRepositoryNames returns the names of the organization's repositories to be used for calculating the metric.
+// It's the union of the RepositoryNames and the names of the active repositories matching the RepositoryNamePatterns.
+func (r *HorizontalRunnerAutoscalerReconciler) listRepositoryNames(ghc *github.Client, org string, metric v1alpha1.MetricSpec) ([]string, error) {
+	repoNames := append([]string{}, metric.RepositoryNames...)
+
+	if len(metric.RepositoryNamePatterns) == 0 {
+		return repoNames, nil
+	}
+
+	seen := map[string]struct{}{}
+	for _, n := range repoNames {
+		seen[n] = struct{}{}
+	}
+
+	repos, err := ghc.ListOrganizationRepositories(context.TODO(), org)
+	if err != nil {
+		return nil, fmt.Errorf("listing repositories of organization %s: %w", org, err)
+	}
+
+	for _, repo := range repos {
+		name := repo.GetName()
+
+		if repo.GetArchived() || repo.GetDisabled() {
+			continue
+		}
+
+		if _, ok := seen[name]; ok {
+			continue
+		}
+
+		if !matchRepositoryNamePatterns(metric.RepositoryNamePatterns, name) {
+			continue
+		}
+
+		seen[name] = struct{}{}
+		repoNames = append(repoNames, name)
+	}
+
+	return repoNames, nil
+}
+
+// matchRepositoryNamePatterns returns true when the name matches any of the patterns and none of the `!`-prefixed ones.
+func matchRepositoryNamePatterns(patterns []string, name string) bool {
+	var matched bool
+
+	for _, p := range patterns {
+		if p == "" {
+			continue
+		}
+
+		if strings.HasPrefix(p, "!") {
+			if !actionsglob.Match(p, name) {
+				return false
+			}
+
+			continue
+		}
+
+		if actionsglob.Match(p, name) {
+			matched = true
+		}
+	}
+
+	return matched
+}
+
+func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPercentageRunnersBusy(ghc *github.Client, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler, metric v1alpha1.MetricSpec) (*v1alpha1.MetricStatus, error) {
 	ctx := context.Background()
 	minReplicas := *hra.Spec.MinReplicas
 	maxReplicas := *hra.Spec.MaxReplicas
-	metrics := hra.Spec.Metrics[0]
 	scaleUpThreshold := defaultScaleUpThreshold
 	scaleDownThreshold := defaultScaleDownThreshold
 	scaleUpFactor := defaultScaleUpFactor
 	scaleDownFactor := defaultScaleDownFactor
 
-	if metrics.ScaleUpThreshold != "" {
-		sut, err := strconv.ParseFloat(metrics.ScaleUpThreshold, 64)
+	if metric.ScaleUpThreshold != "" {
+		sut, err := strconv.ParseFloat(metric.ScaleUpThreshold, 64)
 		if err != nil {
 			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].scaleUpThreshold cannot be parsed into a float64")
 		}
 		scaleUpThreshold = sut
 	}
-	if metrics.ScaleDownThreshold != "" {
-		sdt, err := strconv.ParseFloat(metrics.ScaleDownThreshold, 64)
+	if metric.ScaleDownThreshold != "" {
+		sdt, err := strconv.ParseFloat(metric.ScaleDownThreshold, 64)
 		if err != nil {
 			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].scaleDownThreshold cannot be parsed into a float64")
 		}
@@ -238,34 +434,34 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPercentageRunn
 		scaleDownThreshold = sdt
 	}
 
-	scaleUpAdjustment := metrics.ScaleUpAdjustment
+	scaleUpAdjustment := metric.ScaleUpAdjustment
 	if scaleUpAdjustment != 0 {
-		if metrics.ScaleUpAdjustment < 0 {
+		if metric.ScaleUpAdjustment < 0 {
 			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].scaleUpAdjustment cannot be lower than 0")
 		}
 
-		if metrics.ScaleUpFactor != "" {
+		if metric.ScaleUpFactor != "" {
 			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[]: scaleUpAdjustment and scaleUpFactor cannot be specified together")
 		}
-	} else if metrics.ScaleUpFactor != "" {
-		suf, err := strconv.ParseFloat(metrics.ScaleUpFactor, 64)
+	} else if metric.ScaleUpFactor != "" {
+		suf, err := strconv.ParseFloat(metric.ScaleUpFactor, 64)
 		if err != nil {
 			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].scaleUpFactor cannot be parsed into a float64")
 		}
 		scaleUpFactor = suf
 	}
 
-	scaleDownAdjustment := metrics.ScaleDownAdjustment
+	scaleDownAdjustment := metric.ScaleDownAdjustment
 	if scaleDownAdjustment != 0 {
-		if metrics.ScaleDownAdjustment < 0 {
+		if metric.ScaleDownAdjustment < 0 {
 			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].scaleDownAdjustment cannot be lower than 0")
 		}
 
-		if metrics.ScaleDownFactor != "" {
+		if metric.ScaleDownFactor != "" {
 			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[]: scaleDownAdjustment and scaleDownFactor cannot be specified together")
 		}
-	} else if metrics.ScaleDownFactor != "" {
-		sdf, err := strconv.ParseFloat(metrics.ScaleDownFactor, 64)
+	} else if metric.ScaleDownFactor != "" {
+		sdf, err := strconv.ParseFloat(metric.ScaleDownFactor, 64)
 		if err != nil {
 			return nil, errors.New("validating autoscaling metrics: spec.autoscaling.metrics[].scaleDownFactor cannot be parsed into a float64")
 		}
@@ -310,7 +506,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPercentageRunn
 	)
 
 	// ListRunners will return all runners managed by GitHub - not restricted to ns
-	runners, err := r.GitHubClient.ListRunners(
+	runners, err := ghc.ListRunners(
 		ctx,
 		enterprise,
 		organization,
@@ -327,6 +523,24 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPercentageRunn
 		desiredReplicasBefore = *v
 	}
 
+	// There's no runner to compute the percentage of busy runners against when the deployment is scaled to zero.
+	// It's the webhook-based autoscaler or another metric like TotalNumberOfQueuedAndInProgressWorkflowRuns that
+	// brings the deployment back from zero.
+	if desiredReplicasBefore == 0 {
+		r.Log.V(1).Info(
+			"Skipped calculating desired replicas by the percentage of busy runners because the runner deployment is scaled to zero",
+			"replicas_min", minReplicas,
+			"namespace", hra.Namespace,
+			"runner_deployment", rd.Name,
+			"horizontal_runner_autoscaler", hra.Name,
+		)
+
+		return &v1alpha1.MetricStatus{
+			Type:            v1alpha1.AutoscalingMetricTypePercentageRunnersBusy,
+			DesiredReplicas: minReplicas,
+		}, nil
+	}
+
 	var (
 		numRunners           int
 		numRunnersRegistered int
@@ -345,6 +559,8 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPercentageRunn
 		}
 	}
 
+	metrics.SetRunnerDeploymentBusyRunners(rd.Namespace, rd.Name, numRunnersBusy, numRunnersRegistered)
+
 	var desiredReplicas int
 	fractionBusy := float64(numRunnersBusy) / float64(desiredReplicasBefore)
 	if fractionBusy >= scaleUpThreshold {
@@ -360,7 +576,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPercentageRunn
 			desiredReplicas = int(float64(desiredReplicasBefore) * scaleDownFactor)
 		}
 	} else {
-		desiredReplicas = *rd.Spec.Replicas
+		desiredReplicas = desiredReplicasBefore
 	}
 
 	if desiredReplicas < minReplicas {
@@ -392,7 +608,10 @@ func (r *HorizontalRunnerAutoscalerReconciler) calculateReplicasByPercentageRunn
 	)
 
 	rd.Status.Replicas = &desiredReplicas
-	replicas := desiredReplicas
 
-	return &replicas, nil
+	return &v1alpha1.MetricStatus{
+		Type:            v1alpha1.AutoscalingMetricTypePercentageRunnersBusy,
+		CurrentValue:    strconv.FormatFloat(fractionBusy, 'f', 2, 64),
+		DesiredReplicas: desiredReplicas,
+	}, nil
 }
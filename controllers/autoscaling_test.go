@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http/httptest"
 	"net/url"
+	"reflect"
 	"testing"
 
 	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
@@ -12,6 +13,7 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	kfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
@@ -42,6 +44,7 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 	testcases := []struct {
 		repo      string
 		org       string
+		labels    []string
 		fixed     *int
 		max       *int
 		min       *int
@@ -139,6 +142,28 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 			workflowRuns_in_progress: `{"total_count": 1, "workflow_runs":[{"status":"in_progress"}]}"`,
 			want:                     1,
 		},
+		// 0 demanded, min at 0
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(0),
+			max:                      intPtr(3),
+			workflowRuns:             `{"total_count": 1, "workflow_runs":[{"status":"completed"}]}"`,
+			workflowRuns_queued:      `{"total_count": 0, "workflow_runs":[]}"`,
+			workflowRuns_in_progress: `{"total_count": 0, "workflow_runs":[]}"`,
+			want:                     0,
+		},
+		// 2 demanded, min at 0, currently scaled to zero
+		{
+			repo:                     "test/valid",
+			min:                      intPtr(0),
+			max:                      intPtr(3),
+			fixed:                    intPtr(0),
+			sReplicas:                intPtr(0),
+			workflowRuns:             `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"queued"}]}"`,
+			workflowRuns_queued:      `{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 0, "workflow_runs":[]}"`,
+			want:                     2,
+		},
 		// fixed at 3
 		{
 			repo:                     "test/valid",
@@ -167,6 +192,38 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 			},
 			want: 5,
 		},
+		// 2 requested from 3 workflows, excluding jobs that need labels the runners don't have
+		{
+			repo:                     "test/valid",
+			labels:                   []string{"linux", "gpu"},
+			min:                      intPtr(0),
+			max:                      intPtr(10),
+			workflowRuns:             `{"total_count": 4, "workflow_runs":[{"id": 1, "status":"queued"}, {"id": 2, "status":"in_progress"}, {"id": 3, "status":"in_progress"}, {"status":"completed"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"id": 1, "status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"id": 2, "status":"in_progress"}, {"id": 3, "status":"in_progress"}]}"`,
+			workflowJobs: map[int]string{
+				1: `{"jobs": [{"status":"queued", "labels":["self-hosted", "GPU"]}, {"status":"queued", "labels":["ubuntu-latest"]}]}`,
+				2: `{"jobs": [{"status": "in_progress", "labels":["self-hosted", "linux", "gpu"]}, {"status":"completed", "labels":["self-hosted", "gpu"]}]}`,
+				3: `{"jobs": [{"status": "in_progress", "labels":["self-hosted", "arm64"]}, {"status":"queued", "labels":["self-hosted", "small"]}]}`,
+			},
+			want: 2,
+		},
+		// 1 requested from 3 workflows, on runners of another architecture
+		{
+			repo:                     "test/valid",
+			labels:                   []string{"arm64"},
+			min:                      intPtr(0),
+			max:                      intPtr(10),
+			workflowRuns:             `{"total_count": 4, "workflow_runs":[{"id": 1, "status":"queued"}, {"id": 2, "status":"in_progress"}, {"id": 3, "status":"in_progress"}, {"status":"completed"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"id": 1, "status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"id": 2, "status":"in_progress"}, {"id": 3, "status":"in_progress"}]}"`,
+			workflowJobs: map[int]string{
+				1: `{"jobs": [{"status":"queued", "labels":["self-hosted", "GPU"]}, {"status":"queued", "labels":["ubuntu-latest"]}]}`,
+				2: `{"jobs": [{"status": "in_progress", "labels":["self-hosted", "linux", "gpu"]}, {"status":"completed", "labels":["self-hosted", "gpu"]}]}`,
+				3: `{"jobs": [{"status": "in_progress", "labels":["self-hosted", "arm64"]}, {"status":"queued", "labels":["self-hosted", "small"]}]}`,
+			},
+			want: 1,
+		},
 	}
 
 	for i := range testcases {
@@ -191,7 +248,7 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 
 			h := &HorizontalRunnerAutoscalerReconciler{
 				Log:          log,
-				GitHubClient: client,
+				GitHubClient: NewMultiGitHubClient(nil, client, github.Config{}),
 				Scheme:       scheme,
 			}
 
@@ -204,6 +261,7 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 					Template: v1alpha1.RunnerTemplate{
 						Spec: v1alpha1.RunnerSpec{
 							Repository: tc.repo,
+							Labels:     tc.labels,
 						},
 					},
 					Replicas: tc.fixed,
@@ -224,7 +282,7 @@ func TestDetermineDesiredReplicas_RepositoryRunner(t *testing.T) {
 				},
 			}
 
-			got, err := h.computeReplicas(rd, hra)
+			got, _, _, err := h.computeReplicas(client, rd, hra)
 			if err != nil {
 				if tc.err == "" {
 					t.Fatalf("unexpected error: expected none, got %v", err)
@@ -251,14 +309,18 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 	}
 
 	metav1Now := metav1.Now()
+	orgRepos := `[{"name": "valid"}, {"name": "valid-archived", "archived": true}, {"name": "other"}]`
+
 	testcases := []struct {
-		repos     []string
-		org       string
-		fixed     *int
-		max       *int
-		min       *int
-		sReplicas *int
-		sTime     *metav1.Time
+		repos        []string
+		repoPatterns []string
+		orgRepos     string
+		org          string
+		fixed        *int
+		max          *int
+		min          *int
+		sReplicas    *int
+		sTime        *metav1.Time
 
 		workflowRuns             string
 		workflowRuns_queued      string
@@ -390,7 +452,56 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 			workflowRuns:             `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"completed"}]}"`,
 			workflowRuns_queued:      `{"total_count": 0, "workflow_runs":[]}"`,
 			workflowRuns_in_progress: `{"total_count": 1, "workflow_runs":[{"status":"in_progress"}]}"`,
-			err:                      "validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames is required and must have one more more entries for organizational runner deployment",
+			err:                      "validating autoscaling metrics: spec.autoscaling.metrics[].repositoryNames or repositoryNamePatterns is required and must have one more more entries for organizational runner deployment",
+		},
+		// org runner, 3 demanded, repos found by a pattern skipping archived ones
+		{
+			org:                      "test",
+			repoPatterns:             []string{"val*"},
+			orgRepos:                 orgRepos,
+			min:                      intPtr(1),
+			max:                      intPtr(3),
+			workflowRuns:             `{"total_count": 4, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}, {"status":"completed"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			want:                     3,
+		},
+		// org runner, 3 demanded, all repos but excluded ones
+		{
+			org:                      "test",
+			repoPatterns:             []string{"*", "!other"},
+			orgRepos:                 orgRepos,
+			min:                      intPtr(1),
+			max:                      intPtr(3),
+			workflowRuns:             `{"total_count": 4, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}, {"status":"completed"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			want:                     3,
+		},
+		// org runner, 3 demanded, leading wildcard patterns that other repositories don't contain at all
+		{
+			org:                      "test",
+			repoPatterns:             []string{"*lid", "!*-sandbox"},
+			orgRepos:                 orgRepos,
+			min:                      intPtr(1),
+			max:                      intPtr(3),
+			workflowRuns:             `{"total_count": 4, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}, {"status":"completed"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			want:                     3,
+		},
+		// org runner, pattern combined with the same repository name doesn't count the repository twice
+		{
+			org:                      "test",
+			repos:                    []string{"valid"},
+			repoPatterns:             []string{"valid"},
+			orgRepos:                 orgRepos,
+			min:                      intPtr(1),
+			max:                      intPtr(10),
+			workflowRuns:             `{"total_count": 4, "workflow_runs":[{"status":"queued"}, {"status":"in_progress"}, {"status":"in_progress"}, {"status":"completed"}]}"`,
+			workflowRuns_queued:      `{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+			workflowRuns_in_progress: `{"total_count": 2, "workflow_runs":[{"status":"in_progress"}, {"status":"in_progress"}]}"`,
+			want:                     3,
 		},
 
 		// Job-level autoscaling
@@ -428,6 +539,7 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 				fake.WithListRepositoryWorkflowRunsResponse(200, tc.workflowRuns, tc.workflowRuns_queued, tc.workflowRuns_in_progress),
 				fake.WithListWorkflowJobsResponse(200, tc.workflowJobs),
 				fake.WithListRunnersResponse(200, fake.RunnersListBody),
+				fake.WithListOrganizationRepositoriesResponse(200, tc.orgRepos),
 			)
 			defer server.Close()
 			client := newGithubClient(server)
@@ -435,7 +547,7 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 			h := &HorizontalRunnerAutoscalerReconciler{
 				Log:          log,
 				Scheme:       scheme,
-				GitHubClient: client,
+				GitHubClient: NewMultiGitHubClient(nil, client, github.Config{}),
 			}
 
 			rd := v1alpha1.RunnerDeployment{
@@ -474,8 +586,9 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 					MinReplicas: tc.min,
 					Metrics: []v1alpha1.MetricSpec{
 						{
-							Type:            v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
-							RepositoryNames: tc.repos,
+							Type:                   v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+							RepositoryNames:        tc.repos,
+							RepositoryNamePatterns: tc.repoPatterns,
 						},
 					},
 				},
@@ -485,7 +598,7 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 				},
 			}
 
-			got, err := h.computeReplicas(rd, hra)
+			got, _, _, err := h.computeReplicas(client, rd, hra)
 			if err != nil {
 				if tc.err == "" {
 					t.Fatalf("unexpected error: expected none, got %v", err)
@@ -505,3 +618,353 @@ func TestDetermineDesiredReplicas_OrganizationalRunner(t *testing.T) {
 		})
 	}
 }
+
+func TestDetermineDesiredReplicas_PercentageRunnersBusy(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	busyRunnersBody := `
+{
+  "total_count": 2,
+  "runners": [
+    {"id": 1, "name": "test1", "os": "linux", "status": "online", "busy": true},
+    {"id": 2, "name": "test2", "os": "linux", "status": "online", "busy": true}
+  ]
+}
+`
+
+	testcases := []struct {
+		replicas    *int
+		min         *int
+		max         *int
+		runnersBody string
+		want        int
+	}{
+		// scaled to zero stays at zero
+		{
+			replicas:    intPtr(0),
+			min:         intPtr(0),
+			max:         intPtr(5),
+			runnersBody: busyRunnersBody,
+			want:        0,
+		},
+		// all busy
+		{
+			replicas:    intPtr(2),
+			min:         intPtr(0),
+			max:         intPtr(5),
+			runnersBody: busyRunnersBody,
+			want:        3,
+		},
+		// none busy
+		{
+			replicas:    intPtr(2),
+			min:         intPtr(0),
+			max:         intPtr(5),
+			runnersBody: fake.RunnersListBody,
+			want:        1,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRunnersResponse(200, tc.runnersBody),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			var runners []runtime.Object
+			for _, name := range []string{"test1", "test2"} {
+				runners = append(runners, &v1alpha1.Runner{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: "default",
+						Labels: map[string]string{
+							LabelKeyRunnerDeploymentName: "testrd",
+						},
+					},
+				})
+			}
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Client:       kfake.NewFakeClientWithScheme(scheme, runners...),
+				Log:          log,
+				GitHubClient: NewMultiGitHubClient(nil, client, github.Config{}),
+				Scheme:       scheme,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+					Replicas: tc.replicas,
+				},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MaxReplicas: tc.max,
+					MinReplicas: tc.min,
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy,
+						},
+					},
+				},
+			}
+
+			got, _, _, err := h.computeReplicas(client, rd, hra)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if *got != tc.want {
+				t.Errorf("%d: incorrect desired replicas: want %d, got %d", i, tc.want, *got)
+			}
+		})
+	}
+}
+
+func TestDetermineDesiredReplicas_MultipleMetrics(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	busyRunnersBody := `
+{
+  "total_count": 2,
+  "runners": [
+    {"id": 1, "name": "test1", "os": "linux", "status": "online", "busy": true},
+    {"id": 2, "name": "test2", "os": "linux", "status": "online", "busy": true}
+  ]
+}
+`
+
+	// TotalNumberOfQueuedAndInProgressWorkflowRuns wants 1 replica and PercentageRunnersBusy wants 3 replicas
+	testcases := []struct {
+		policy string
+		max    *int
+		want   int
+		err    string
+	}{
+		{
+			policy: "",
+			max:    intPtr(5),
+			want:   3,
+		},
+		{
+			policy: v1alpha1.MetricsPolicyMax,
+			max:    intPtr(5),
+			want:   3,
+		},
+		{
+			policy: v1alpha1.MetricsPolicyMin,
+			max:    intPtr(5),
+			want:   1,
+		},
+		{
+			policy: v1alpha1.MetricsPolicySum,
+			max:    intPtr(5),
+			want:   4,
+		},
+		// sum capped at max
+		{
+			policy: v1alpha1.MetricsPolicySum,
+			max:    intPtr(3),
+			want:   3,
+		},
+		{
+			policy: "Avg",
+			max:    intPtr(5),
+			err:    `validating autoscaling metrics: unsupported metrics policy "Avg"`,
+		},
+	}
+
+	for i := range testcases {
+		tc := testcases[i]
+
+		log := zap.New(func(o *zap.Options) {
+			o.Development = true
+		})
+
+		scheme := runtime.NewScheme()
+		_ = clientgoscheme.AddToScheme(scheme)
+		_ = v1alpha1.AddToScheme(scheme)
+
+		t.Run(fmt.Sprintf("case %d", i), func(t *testing.T) {
+			server := fake.NewServer(
+				fake.WithListRepositoryWorkflowRunsResponse(200,
+					`{"total_count": 2, "workflow_runs":[{"status":"queued"}, {"status":"completed"}]}"`,
+					`{"total_count": 1, "workflow_runs":[{"status":"queued"}]}"`,
+					`{"total_count": 0, "workflow_runs":[]}"`,
+				),
+				fake.WithListRunnersResponse(200, busyRunnersBody),
+			)
+			defer server.Close()
+			client := newGithubClient(server)
+
+			var runners []runtime.Object
+			for _, name := range []string{"test1", "test2"} {
+				runners = append(runners, &v1alpha1.Runner{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:      name,
+						Namespace: "default",
+						Labels: map[string]string{
+							LabelKeyRunnerDeploymentName: "testrd",
+						},
+					},
+				})
+			}
+
+			h := &HorizontalRunnerAutoscalerReconciler{
+				Client:       kfake.NewFakeClientWithScheme(scheme, runners...),
+				Log:          log,
+				GitHubClient: NewMultiGitHubClient(nil, client, github.Config{}),
+				Scheme:       scheme,
+			}
+
+			rd := v1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: v1alpha1.RunnerDeploymentSpec{
+					Template: v1alpha1.RunnerTemplate{
+						Spec: v1alpha1.RunnerSpec{
+							Repository: "test/valid",
+						},
+					},
+					Replicas: intPtr(2),
+				},
+			}
+
+			hra := v1alpha1.HorizontalRunnerAutoscaler{
+				Spec: v1alpha1.HorizontalRunnerAutoscalerSpec{
+					MaxReplicas:   tc.max,
+					MinReplicas:   intPtr(0),
+					MetricsPolicy: tc.policy,
+					Metrics: []v1alpha1.MetricSpec{
+						{
+							Type: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns,
+						},
+						{
+							Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy,
+						},
+					},
+				},
+			}
+
+			got, statuses, _, err := h.computeReplicas(client, rd, hra)
+			if err != nil {
+				if tc.err == "" {
+					t.Fatalf("unexpected error: expected none, got %v", err)
+				} else if err.Error() != tc.err {
+					t.Fatalf("unexpected error: expected %v, got %v", tc.err, err)
+				}
+				return
+			} else if tc.err != "" {
+				t.Fatalf("expected error %q, got none", tc.err)
+			}
+
+			if *got != tc.want {
+				t.Errorf("%d: incorrect desired replicas: want %d, got %d", i, tc.want, *got)
+			}
+
+			wantStatuses := []v1alpha1.MetricStatus{
+				{Type: v1alpha1.AutoscalingMetricTypeTotalNumberOfQueuedAndInProgressWorkflowRuns, CurrentValue: "1", DesiredReplicas: 1},
+				{Type: v1alpha1.AutoscalingMetricTypePercentageRunnersBusy, CurrentValue: "1.00", DesiredReplicas: 3},
+			}
+
+			if !reflect.DeepEqual(statuses, wantStatuses) {
+				t.Errorf("%d: unexpected metric statuses: want %+v, got %+v", i, wantStatuses, statuses)
+			}
+		})
+	}
+}
+
+func TestCanRunJob(t *testing.T) {
+	testcases := []struct {
+		specLabels   []string
+		commonLabels []string
+		jobLabels    []string
+		want         bool
+	}{
+		{
+			jobLabels: nil,
+			want:      true,
+		},
+		{
+			jobLabels: []string{"self-hosted"},
+			want:      true,
+		},
+		{
+			jobLabels: []string{"Self-Hosted"},
+			want:      true,
+		},
+		{
+			jobLabels: []string{"self-hosted", "linux", "x64"},
+			want:      false,
+		},
+		{
+			specLabels: []string{"linux", "x64"},
+			jobLabels:  []string{"Self-Hosted", "Linux", "X64"},
+			want:       true,
+		},
+		{
+			specLabels: []string{"linux", "arm64"},
+			jobLabels:  []string{"self-hosted", "linux", "arm64"},
+			want:       true,
+		},
+		{
+			specLabels: []string{"linux", "arm64"},
+			jobLabels:  []string{"self-hosted", "linux", "x64"},
+			want:       false,
+		},
+		{
+			jobLabels: []string{"ubuntu-latest"},
+			want:      false,
+		},
+		{
+			specLabels: []string{"gpu"},
+			jobLabels:  []string{"self-hosted", "gpu"},
+			want:       true,
+		},
+		{
+			commonLabels: []string{"on-prem"},
+			jobLabels:    []string{"self-hosted", "on-prem"},
+			want:         true,
+		},
+		{
+			specLabels:   []string{"gpu"},
+			commonLabels: []string{"on-prem"},
+			jobLabels:    []string{"self-hosted", "gpu", "large"},
+			want:         false,
+		},
+	}
+
+	for i, tc := range testcases {
+		got := canRunJob(runnerLabelSet(tc.specLabels, tc.commonLabels), tc.jobLabels)
+		if got != tc.want {
+			t.Errorf("%d: unexpected result: want %v, got %v", i, tc.want, got)
+		}
+	}
+}
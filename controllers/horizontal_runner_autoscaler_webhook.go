@@ -35,6 +35,8 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/controllers/metrics"
+	"github.com/summerwind/actions-runner-controller/github"
 )
 
 const (
@@ -58,6 +60,10 @@ type HorizontalRunnerAutoscalerGitHubWebhook struct {
 	// Set to empty for letting it watch for all namespaces.
 	Namespace string
 	Name      string
+
+	// CommonRunnerLabels is the list of labels added to every runner by the controller.
+	// It's used to find the runner deployment whose runners can run the job on workflow_job events.
+	CommonRunnerLabels []string
 }
 
 func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Reconcile(request reconcile.Request) (reconcile.Result, error) {
@@ -120,7 +126,16 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 	}
 
 	webhookType := gogithub.WebHookType(r)
-	event, err := gogithub.ParseWebHook(webhookType, payload)
+
+	metrics.IncGitHubWebhookEventsReceived(webhookType)
+
+	var event interface{}
+
+	if webhookType == workflowJobEventType {
+		event, err = parseWorkflowJobEvent(payload)
+	} else {
+		event, err = gogithub.ParseWebHook(webhookType, payload)
+	}
 	if err != nil {
 		var s string
 		if payload != nil {
@@ -132,7 +147,13 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		return
 	}
 
-	var target *ScaleTarget
+	var (
+		target *ScaleTarget
+
+		// scaleDown is set to true when the event signals that a previously added capacity reservation
+		// is no longer needed.
+		scaleDown bool
+	)
 
 	log := autoscaler.Log.WithValues(
 		"event", webhookType,
@@ -182,9 +203,50 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 				"action", e.GetAction(),
 			)
 		}
+	case *github.WorkflowJobEvent:
+		if workflowJob := e.GetWorkflowJob(); workflowJob != nil {
+			log = log.WithValues(
+				"workflowJob.id", workflowJob.GetID(),
+				"workflowJob.runID", workflowJob.GetRunID(),
+				"workflowJob.status", workflowJob.GetStatus(),
+				"action", e.GetAction(),
+			)
+		}
+
+		switch action := e.GetAction(); action {
+		case workflowJobActionQueued, workflowJobActionCompleted:
+			target, err = autoscaler.getScaleUpTarget(
+				context.TODO(),
+				log,
+				e.Repo.GetName(),
+				e.Repo.Owner.GetLogin(),
+				e.Repo.Owner.GetType(),
+				autoscaler.MatchWorkflowJobEvent(e),
+			)
+
+			scaleDown = action == workflowJobActionCompleted
+		default:
+			ok = true
+
+			metrics.IncGitHubWebhookEventsIgnored(webhookType)
+
+			w.WriteHeader(http.StatusOK)
+
+			msg := fmt.Sprintf("no scaling needed for workflow_job event with action %q", action)
+
+			log.V(1).Info(msg)
+
+			if written, err := w.Write([]byte(msg)); err != nil {
+				log.Error(err, "failed writing http response", "msg", msg, "written", written)
+			}
+
+			return
+		}
 	case *gogithub.PingEvent:
 		ok = true
 
+		metrics.IncGitHubWebhookEventsIgnored(webhookType)
+
 		w.WriteHeader(http.StatusOK)
 
 		msg := "pong"
@@ -197,13 +259,15 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 
 		return
 	default:
+		metrics.IncGitHubWebhookEventsIgnored(webhookType)
+
 		log.Info("unknown event type", "eventType", webhookType)
 
 		return
 	}
 
 	if err != nil {
-		log.Error(err, "handling check_run event")
+		log.Error(err, "handling event")
 
 		return
 	}
@@ -217,6 +281,8 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 
 		ok = true
 
+		metrics.IncGitHubWebhookEventsIgnored(webhookType)
+
 		w.WriteHeader(http.StatusOK)
 
 		if written, err := w.Write([]byte(msg)); err != nil {
@@ -226,17 +292,34 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) Handle(w http.Respons
 		return
 	}
 
-	if err := autoscaler.tryScaleUp(context.TODO(), target); err != nil {
-		log.Error(err, "could not scale up")
+	amount := 1
+
+	if target.ScaleUpTrigger.Amount > 0 {
+		amount = target.ScaleUpTrigger.Amount
+	}
+
+	if scaleDown {
+		amount = -amount
+	}
+
+	scaled, err := autoscaler.tryScale(context.TODO(), target, amount)
+	if err != nil {
+		log.Error(err, "could not scale")
 
 		return
 	}
 
 	ok = true
 
+	metrics.IncGitHubWebhookEventsMatched(webhookType)
+
 	w.WriteHeader(http.StatusOK)
 
-	msg := fmt.Sprintf("scaled %s by 1", target.Name)
+	msg := fmt.Sprintf("scaled %s by %d", target.Name, amount)
+
+	if !scaled {
+		msg = fmt.Sprintf("no reservation to remove from %s", target.Name)
+	}
 
 	autoscaler.Log.Info(msg)
 
@@ -301,7 +384,7 @@ type ScaleTarget struct {
 	v1alpha1.ScaleUpTrigger
 }
 
-func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) searchScaleTargets(hras []v1alpha1.HorizontalRunnerAutoscaler, f func(v1alpha1.ScaleUpTrigger) bool) []ScaleTarget {
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) searchScaleTargets(hras []v1alpha1.HorizontalRunnerAutoscaler, f func(ScaleTarget) bool) []ScaleTarget {
 	var matched []ScaleTarget
 
 	for _, hra := range hras {
@@ -310,21 +393,23 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) searchScaleTargets(hr
 		}
 
 		for _, scaleUpTrigger := range hra.Spec.ScaleUpTriggers {
-			if !f(scaleUpTrigger) {
+			target := ScaleTarget{
+				HorizontalRunnerAutoscaler: hra,
+				ScaleUpTrigger:             scaleUpTrigger,
+			}
+
+			if !f(target) {
 				continue
 			}
 
-			matched = append(matched, ScaleTarget{
-				HorizontalRunnerAutoscaler: hra,
-				ScaleUpTrigger:             scaleUpTrigger,
-			})
+			matched = append(matched, target)
 		}
 	}
 
 	return matched
 }
 
-func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleTarget(ctx context.Context, name string, f func(v1alpha1.ScaleUpTrigger) bool) (*ScaleTarget, error) {
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleTarget(ctx context.Context, name string, f func(ScaleTarget) bool) (*ScaleTarget, error) {
 	hras, err := autoscaler.findHRAsByKey(ctx, name)
 	if err != nil {
 		return nil, err
@@ -358,7 +443,7 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleTarget(ctx co
 	return &targets[0], nil
 }
 
-func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTarget(ctx context.Context, log logr.Logger, repo, owner, ownerType string, f func(v1alpha1.ScaleUpTrigger) bool) (*ScaleTarget, error) {
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTarget(ctx context.Context, log logr.Logger, repo, owner, ownerType string, f func(ScaleTarget) bool) (*ScaleTarget, error) {
 	repositoryRunnerKey := owner + "/" + repo
 
 	if target, err := autoscaler.getScaleTarget(ctx, repositoryRunnerKey, f); err != nil {
@@ -384,31 +469,58 @@ func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) getScaleUpTarget(ctx
 	return nil, nil
 }
 
-func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) tryScaleUp(ctx context.Context, target *ScaleTarget) error {
+// tryScale adds a capacity reservation of the given amount of replicas to the target when amount is positive.
+// When amount is negative, it removes the oldest valid capacity reservation of -amount replicas instead.
+// It returns false when there was no such reservation to remove.
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) tryScale(ctx context.Context, target *ScaleTarget, amount int) (bool, error) {
 	if target == nil {
-		return nil
+		return false, nil
 	}
 
 	copy := target.HorizontalRunnerAutoscaler.DeepCopy()
 
-	amount := 1
+	capacityReservations := getValidCapacityReservations(copy)
 
-	if target.ScaleUpTrigger.Amount > 0 {
-		amount = target.ScaleUpTrigger.Amount
-	}
+	if amount > 0 {
+		copy.Spec.CapacityReservations = append(capacityReservations, v1alpha1.CapacityReservation{
+			ExpirationTime: metav1.Time{Time: time.Now().Add(target.ScaleUpTrigger.Duration.Duration)},
+			Replicas:       amount,
+		})
+	} else if amount < 0 {
+		var removed bool
 
-	capacityReservations := getValidCapacityReservations(copy)
+		copy.Spec.CapacityReservations, removed = removeCapacityReservation(capacityReservations, -amount)
 
-	copy.Spec.CapacityReservations = append(capacityReservations, v1alpha1.CapacityReservation{
-		ExpirationTime: metav1.Time{Time: time.Now().Add(target.ScaleUpTrigger.Duration.Duration)},
-		Replicas:       amount,
-	})
+		if !removed {
+			return false, nil
+		}
+	}
 
 	if err := autoscaler.Client.Patch(ctx, copy, client.MergeFrom(&target.HorizontalRunnerAutoscaler)); err != nil {
-		return fmt.Errorf("patching horizontalrunnerautoscaler to add capacity reservation: %w", err)
+		return false, fmt.Errorf("patching horizontalrunnerautoscaler to update capacity reservations: %w", err)
+	}
+
+	return true, nil
+}
+
+// removeCapacityReservation removes the first capacity reservation that has exactly the given number of replicas.
+// It returns false when none has.
+func removeCapacityReservation(reservations []v1alpha1.CapacityReservation, replicas int) ([]v1alpha1.CapacityReservation, bool) {
+	var (
+		result  []v1alpha1.CapacityReservation
+		removed bool
+	)
+
+	for _, r := range reservations {
+		if !removed && r.Replicas == replicas {
+			removed = true
+			continue
+		}
+
+		result = append(result, r)
 	}
 
-	return nil
+	return result, removed
 }
 
 func getValidCapacityReservations(autoscaler *v1alpha1.HorizontalRunnerAutoscaler) []v1alpha1.CapacityReservation {
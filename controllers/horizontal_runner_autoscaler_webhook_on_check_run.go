@@ -2,13 +2,12 @@ package controllers
 
 import (
 	"github.com/google/go-github/v33/github"
-	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
 	"github.com/summerwind/actions-runner-controller/pkg/actionsglob"
 )
 
-func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchCheckRunEvent(event *github.CheckRunEvent) func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
-	return func(scaleUpTrigger v1alpha1.ScaleUpTrigger) bool {
-		g := scaleUpTrigger.GitHubEvent
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchCheckRunEvent(event *github.CheckRunEvent) func(target ScaleTarget) bool {
+	return func(target ScaleTarget) bool {
+		g := target.GitHubEvent
 
 		if g == nil {
 			return false
@@ -0,0 +1,62 @@
+package controllers
+
+import (
+	"context"
+	"encoding/json"
+
+	"k8s.io/apimachinery/pkg/types"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
+)
+
+const (
+	workflowJobEventType = "workflow_job"
+
+	workflowJobActionQueued    = "queued"
+	workflowJobActionCompleted = "completed"
+)
+
+func parseWorkflowJobEvent(payload []byte) (*github.WorkflowJobEvent, error) {
+	var e github.WorkflowJobEvent
+
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, err
+	}
+
+	return &e, nil
+}
+
+func (autoscaler *HorizontalRunnerAutoscalerGitHubWebhook) MatchWorkflowJobEvent(event *github.WorkflowJobEvent) func(target ScaleTarget) bool {
+	return func(target ScaleTarget) bool {
+		g := target.GitHubEvent
+
+		if g == nil {
+			return false
+		}
+
+		workflowJob := g.WorkflowJob
+
+		if workflowJob == nil {
+			return false
+		}
+
+		// A job that can't be run by the runners of the deployment, like the one for GitHub-hosted runners
+		// or another deployment with different labels, shouldn't result in scaling the deployment.
+		var rd v1alpha1.RunnerDeployment
+
+		if err := autoscaler.Client.Get(context.TODO(), types.NamespacedName{Namespace: target.Namespace, Name: target.Spec.ScaleTargetRef.Name}, &rd); err != nil {
+			autoscaler.Log.Error(err, "finding runnerdeployment of horizontalrunnerautoscaler", "horizontalrunnerautoscaler", target.Name)
+
+			return false
+		}
+
+		var jobLabels []string
+
+		if job := event.GetWorkflowJob(); job != nil {
+			jobLabels = job.Labels
+		}
+
+		return canRunJob(runnerLabelSet(rd.Spec.Template.Spec.Labels, autoscaler.CommonRunnerLabels), jobLabels)
+	}
+}
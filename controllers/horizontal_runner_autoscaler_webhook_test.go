@@ -2,20 +2,24 @@ package controllers
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"github.com/go-logr/logr"
 	"github.com/google/go-github/v33/github"
 	actionsv1alpha1 "github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	github2 "github.com/summerwind/actions-runner-controller/github"
 	"io"
 	"io/ioutil"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
+	"reflect"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 	"testing"
 	"time"
@@ -102,6 +106,163 @@ func TestWebhookPush(t *testing.T) {
 	)
 }
 
+func TestWebhookWorkflowJob(t *testing.T) {
+	testcases := []struct {
+		action   string
+		wantBody string
+	}{
+		{
+			action:   "queued",
+			wantBody: "no horizontalrunnerautoscaler to scale for this github event",
+		},
+		{
+			action:   "completed",
+			wantBody: "no horizontalrunnerautoscaler to scale for this github event",
+		},
+		{
+			action:   "in_progress",
+			wantBody: `no scaling needed for workflow_job event with action "in_progress"`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.action, func(t *testing.T) {
+			testServer(t,
+				"workflow_job",
+				&github2.WorkflowJobEvent{
+					WorkflowJob: &github2.WorkflowJob{
+						WorkflowJob: github.WorkflowJob{
+							ID:     github.Int64(1),
+							Status: github.String(tc.action),
+						},
+						Labels: []string{"self-hosted"},
+					},
+					Action: github.String(tc.action),
+					Repo: &github.Repository{
+						Name: github.String("myrepo"),
+						Owner: &github.User{
+							Login: github.String("myorg"),
+							Type:  github.String("Organization"),
+						},
+					},
+				},
+				200,
+				tc.wantBody,
+			)
+		})
+	}
+}
+
+func TestWebhookWorkflowJobWithLabels(t *testing.T) {
+	newRD := func(name string, labels ...string) *actionsv1alpha1.RunnerDeployment {
+		return &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					Spec: actionsv1alpha1.RunnerSpec{
+						Repository: "myorg/myrepo",
+						Labels:     labels,
+					},
+				},
+			},
+		}
+	}
+
+	newHRA := func(name, rdName string) *actionsv1alpha1.HorizontalRunnerAutoscaler {
+		return &actionsv1alpha1.HorizontalRunnerAutoscaler{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      name,
+				Namespace: "default",
+			},
+			Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+				ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+					Name: rdName,
+				},
+				ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+					{
+						GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{
+							WorkflowJob: &actionsv1alpha1.WorkflowJobSpec{},
+						},
+						Amount:   1,
+						Duration: metav1.Duration{Duration: 10 * time.Minute},
+					},
+				},
+			},
+		}
+	}
+
+	testcases := []struct {
+		name     string
+		action   string
+		labels   []string
+		wantBody string
+	}{
+		{
+			name:     "only the gpu runners can run the job",
+			action:   "queued",
+			labels:   []string{"self-hosted", "GPU"},
+			wantBody: "scaled gpurunners by 1",
+		},
+		{
+			name:     "only the common label is requested",
+			action:   "queued",
+			labels:   []string{"self-hosted", "myorg"},
+			wantBody: "no horizontalrunnerautoscaler to scale for this github event",
+		},
+		{
+			name:     "github-hosted runners",
+			action:   "queued",
+			labels:   []string{"ubuntu-latest"},
+			wantBody: "no horizontalrunnerautoscaler to scale for this github event",
+		},
+		{
+			name:     "completed without reservation",
+			action:   "completed",
+			labels:   []string{"self-hosted", "gpu"},
+			wantBody: "no reservation to remove from gpurunners",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			initObjs := []runtime.Object{
+				newRD("cpu", "cpu"),
+				newRD("gpu", "gpu"),
+				newHRA("cpurunners", "cpu"),
+				newHRA("gpurunners", "gpu"),
+			}
+
+			testServerWithInitObjs(t,
+				"workflow_job",
+				&github2.WorkflowJobEvent{
+					WorkflowJob: &github2.WorkflowJob{
+						WorkflowJob: github.WorkflowJob{
+							ID:     github.Int64(1),
+							Status: github.String(tc.action),
+						},
+						Labels: tc.labels,
+					},
+					Action: github.String(tc.action),
+					Repo: &github.Repository{
+						Name: github.String("myrepo"),
+						Owner: &github.User{
+							Login: github.String("myorg"),
+							Type:  github.String("Organization"),
+						},
+					},
+				},
+				200,
+				tc.wantBody,
+				initObjs,
+				[]string{"myorg"},
+			)
+		})
+	}
+}
+
 func TestWebhookPing(t *testing.T) {
 	testServer(t,
 		"ping",
@@ -163,6 +324,84 @@ func TestGetValidCapacityReservations(t *testing.T) {
 	}
 }
 
+func TestTryScale(t *testing.T) {
+	hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myhra",
+			Namespace: "default",
+		},
+	}
+
+	hraWebhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		Client: fake.NewFakeClientWithScheme(sc, hra),
+	}
+
+	trigger := actionsv1alpha1.ScaleUpTrigger{
+		Duration: metav1.Duration{Duration: 10 * time.Minute},
+	}
+
+	for i, step := range []struct {
+		amount     int
+		wantScaled bool
+	}{
+		{amount: 1, wantScaled: true},
+		{amount: 1, wantScaled: true},
+		{amount: -1, wantScaled: true},
+		{amount: -2, wantScaled: false},
+	} {
+		var current actionsv1alpha1.HorizontalRunnerAutoscaler
+
+		if err := hraWebhook.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "myhra"}, &current); err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		scaled, err := hraWebhook.tryScale(context.Background(), &ScaleTarget{HorizontalRunnerAutoscaler: current, ScaleUpTrigger: trigger}, step.amount)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		if scaled != step.wantScaled {
+			t.Errorf("step %d: unexpected result of scaling by %d: want %v, got %v", i, step.amount, step.wantScaled, scaled)
+		}
+	}
+
+	var updated actionsv1alpha1.HorizontalRunnerAutoscaler
+
+	if err := hraWebhook.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "myhra"}, &updated); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if n := len(updated.Spec.CapacityReservations); n != 1 {
+		t.Errorf("unexpected number of capacity reservations: want 1, got %d", n)
+	}
+}
+
+func TestRemoveCapacityReservation(t *testing.T) {
+	reservations := []actionsv1alpha1.CapacityReservation{
+		{Name: "a", Replicas: 2},
+		{Name: "b", Replicas: 1},
+		{Name: "c", Replicas: 1},
+	}
+
+	got, removed := removeCapacityReservation(reservations, 1)
+	if !removed {
+		t.Errorf("a reservation should be reported as removed")
+	}
+
+	var names []string
+	for _, r := range got {
+		names = append(names, r.Name)
+	}
+
+	if want := []string{"a", "c"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("want %v, got %v", want, names)
+	}
+
+	if got, removed := removeCapacityReservation(reservations, 3); removed || len(got) != len(reservations) {
+		t.Errorf("no reservation should be removed when none matches, but got %v", got)
+	}
+}
+
 func installTestLogger(webhook *HorizontalRunnerAutoscalerGitHubWebhook) *bytes.Buffer {
 	logs := &bytes.Buffer{}
 
@@ -179,9 +418,15 @@ func installTestLogger(webhook *HorizontalRunnerAutoscalerGitHubWebhook) *bytes.
 func testServer(t *testing.T, eventType string, event interface{}, wantCode int, wantBody string) {
 	t.Helper()
 
-	hraWebhook := &HorizontalRunnerAutoscalerGitHubWebhook{}
+	testServerWithInitObjs(t, eventType, event, wantCode, wantBody, nil, nil)
+}
+
+func testServerWithInitObjs(t *testing.T, eventType string, event interface{}, wantCode int, wantBody string, initObjs []runtime.Object, commonRunnerLabels []string) {
+	t.Helper()
 
-	var initObjs []runtime.Object
+	hraWebhook := &HorizontalRunnerAutoscalerGitHubWebhook{
+		CommonRunnerLabels: commonRunnerLabels,
+	}
 
 	client := fake.NewFakeClientWithScheme(sc, initObjs...)
 
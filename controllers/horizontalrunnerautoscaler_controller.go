@@ -21,7 +21,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/summerwind/actions-runner-controller/controllers/metrics"
 	"github.com/summerwind/actions-runner-controller/github"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/go-logr/logr"
@@ -43,13 +46,18 @@ const (
 // HorizontalRunnerAutoscalerReconciler reconciles a HorizontalRunnerAutoscaler object
 type HorizontalRunnerAutoscalerReconciler struct {
 	client.Client
-	GitHubClient *github.Client
+	GitHubClient *MultiGitHubClient
 	Log          logr.Logger
 	Recorder     record.EventRecorder
 	Scheme       *runtime.Scheme
 
 	CacheDuration time.Duration
-	Name          string
+
+	// CommonRunnerLabels is the list of labels added to every runner by the controller.
+	// It's used to tell if a runner deployment can run a workflow job requesting specific labels.
+	CommonRunnerLabels []string
+
+	Name string
 }
 
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnerdeployments,verbs=get;list;watch;update;patch
@@ -64,10 +72,16 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(req ctrl.Request) (ctrl
 
 	var hra v1alpha1.HorizontalRunnerAutoscaler
 	if err := r.Get(ctx, req.NamespacedName, &hra); err != nil {
+		if kerrors.IsNotFound(err) {
+			metrics.DeleteHorizontalRunnerAutoscaler(req.Namespace, req.Name)
+		}
+
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
 	if !hra.ObjectMeta.DeletionTimestamp.IsZero() {
+		metrics.DeleteHorizontalRunnerAutoscaler(hra.Namespace, hra.Name)
+
 		return ctrl.Result{}, nil
 	}
 
@@ -83,23 +97,79 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(req ctrl.Request) (ctrl
 		return ctrl.Result{}, nil
 	}
 
-	var replicas *int
+	now := time.Now()
+
+	// updated accumulates the changes to the status, which is patched only when it has changed.
+	updated := hra.DeepCopy()
+
+	override, nextScheduledOverrideTransition, err := getScheduledOverride(hra.Spec.ScheduledOverrides, now)
+	if err != nil {
+		r.Recorder.Event(&hra, corev1.EventTypeNormal, "RunnerAutoscalingFailure", err.Error())
+
+		log.Error(err, "Could not determine scheduled override")
+
+		setHorizontalRunnerAutoscalerCondition(updated, v1alpha1.ScalingActive, corev1.ConditionFalse, "InvalidScheduledOverride", err.Error(), now)
+
+		return ctrl.Result{}, r.patchStatusOnError(ctx, &hra, updated, err)
+	}
+
+	// autoscaler is the autoscaler with its minReplicas and maxReplicas overridden by the active scheduled override, if any.
+	// hra is kept as-is so that the status patch below is computed against what's stored in the cluster.
+	autoscaler := hra.DeepCopy()
+
+	if override != nil {
+		applyScheduledOverride(autoscaler, override)
+
+		log.V(1).Info(
+			"Applying scheduled override",
+			"schedule", override.Schedule,
+			"time_zone", override.TimeZone,
+			"replicas_min", autoscaler.Spec.MinReplicas,
+			"replicas_max", autoscaler.Spec.MaxReplicas,
+		)
+	}
+
+	var (
+		replicas         *int
+		metricStatuses   []v1alpha1.MetricStatus
+		scaleDownDelayed bool
+	)
 
 	replicasFromCache := r.getDesiredReplicasFromCache(hra)
 
 	if replicasFromCache != nil {
 		replicas = replicasFromCache
 	} else {
-		var err error
+		var ghc *github.Client
 
-		replicas, err = r.computeReplicas(rd, hra)
+		ghc, err = r.GitHubClient.Init(ctx, rd.Namespace, rd.Spec.Template.Spec.GitHubAPICredentialsFrom)
+		if err != nil {
+			r.Recorder.Event(&hra, corev1.EventTypeNormal, "RunnerAutoscalingFailure", err.Error())
+
+			log.Error(err, "Could not get GitHub client")
+
+			setHorizontalRunnerAutoscalerCondition(updated, v1alpha1.ScalingActive, corev1.ConditionFalse, "FailedGetGitHubClient", err.Error(), now)
+
+			return ctrl.Result{}, r.patchStatusOnError(ctx, &hra, updated, err)
+		}
+
+		replicas, metricStatuses, scaleDownDelayed, err = r.computeReplicas(ghc, rd, *autoscaler)
 		if err != nil {
 			r.Recorder.Event(&hra, corev1.EventTypeNormal, "RunnerAutoscalingFailure", err.Error())
 
 			log.Error(err, "Could not compute replicas")
 
-			return ctrl.Result{}, err
+			setHorizontalRunnerAutoscalerCondition(updated, v1alpha1.ScalingActive, corev1.ConditionFalse, "FailedComputeReplicas", err.Error(), now)
+
+			if retryAt, ok := github.RetryAt(err); ok {
+				// Keep the current replicas and retry once the rate limit resets
+				return ctrl.Result{RequeueAfter: requeueAfter(retryAt)}, r.patchStatusOnError(ctx, &hra, updated, nil)
+			}
+
+			return ctrl.Result{}, r.patchStatusOnError(ctx, &hra, updated, err)
 		}
+
+		setHorizontalRunnerAutoscalerCondition(updated, v1alpha1.ScalingActive, corev1.ConditionTrue, "ValidMetricFound", "the desired replicas was successfully computed", now)
 	}
 
 	const defaultReplicas = 1
@@ -107,33 +177,72 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(req ctrl.Request) (ctrl
 	currentDesiredReplicas := getIntOrDefault(rd.Spec.Replicas, defaultReplicas)
 	newDesiredReplicas := getIntOrDefault(replicas, defaultReplicas)
 
-	now := time.Now()
+	var reservedReplicas int
 
 	for _, reservation := range hra.Spec.CapacityReservations {
 		if reservation.ExpirationTime.Time.After(now) {
-			newDesiredReplicas += reservation.Replicas
+			reservedReplicas += reservation.Replicas
 		}
 	}
 
-	if hra.Spec.MaxReplicas != nil && *hra.Spec.MaxReplicas < newDesiredReplicas {
-		newDesiredReplicas = *hra.Spec.MaxReplicas
+	newDesiredReplicas += reservedReplicas
+
+	var limited string
+
+	// Like HorizontalPodAutoscaler, the desired replicas are limited only when they are out of the range,
+	// so that the desired replicas at the bound isn't reported as limited.
+	if autoscaler.Spec.MaxReplicas != nil && newDesiredReplicas > *autoscaler.Spec.MaxReplicas {
+		newDesiredReplicas = *autoscaler.Spec.MaxReplicas
+
+		limited = fmt.Sprintf("limited by maxReplicas %d", newDesiredReplicas)
+
+		setHorizontalRunnerAutoscalerCondition(updated, v1alpha1.ScalingLimited, corev1.ConditionTrue, "TooManyReplicas", fmt.Sprintf("the desired replicas is %s", limited), now)
+	} else if autoscaler.Spec.MinReplicas != nil && newDesiredReplicas < *autoscaler.Spec.MinReplicas {
+		// The desired replicas can be cached from before a scheduled override with a larger minReplicas became active.
+		newDesiredReplicas = *autoscaler.Spec.MinReplicas
+
+		limited = fmt.Sprintf("limited by minReplicas %d", newDesiredReplicas)
+
+		setHorizontalRunnerAutoscalerCondition(updated, v1alpha1.ScalingLimited, corev1.ConditionTrue, "TooFewReplicas", fmt.Sprintf("the desired replicas is %s", limited), now)
+	} else {
+		setHorizontalRunnerAutoscalerCondition(updated, v1alpha1.ScalingLimited, corev1.ConditionFalse, "DesiredWithinRange", "the desired replicas is within the acceptable range", now)
 	}
 
 	// Please add more conditions that we can in-place update the newest runnerreplicaset without disruption
-	if currentDesiredReplicas != newDesiredReplicas {
+	if hra.Spec.Paused {
+		log.V(1).Info("Skipped scaling runnerdeployment as the autoscaler is paused", "runner_deployment", rd.Name, "replicas", currentDesiredReplicas, "desired_replicas", newDesiredReplicas)
+
+		setHorizontalRunnerAutoscalerCondition(updated, v1alpha1.AbleToScale, corev1.ConditionFalse, "ScalingPaused", fmt.Sprintf("the autoscaler is paused, keeping the runnerdeployment at %d replicas while %d replicas are desired", currentDesiredReplicas, newDesiredReplicas), now)
+	} else if currentDesiredReplicas != newDesiredReplicas {
 		copy := rd.DeepCopy()
 		copy.Spec.Replicas = &newDesiredReplicas
 
 		if err := r.Client.Patch(ctx, copy, client.MergeFrom(&rd)); err != nil {
-			return ctrl.Result{}, fmt.Errorf("patching runnerdeployment to have %d replicas: %w", newDesiredReplicas, err)
+			err = fmt.Errorf("patching runnerdeployment to have %d replicas: %w", newDesiredReplicas, err)
+
+			setHorizontalRunnerAutoscalerCondition(updated, v1alpha1.AbleToScale, corev1.ConditionFalse, "FailedUpdateScale", err.Error(), now)
+
+			return ctrl.Result{}, r.patchStatusOnError(ctx, &hra, updated, err)
 		}
-	}
 
-	var updated *v1alpha1.HorizontalRunnerAutoscaler
+		reason := describeScale(replicasFromCache != nil, getIntOrDefault(replicas, defaultReplicas), metricStatuses, autoscaler.Spec.MetricsPolicy, scaleDownDelayed, reservedReplicas, limited, override)
+
+		r.Recorder.Eventf(&hra, corev1.EventTypeNormal, "SuccessfulRescale", "Scaled runnerdeployment %s from %d to %d replicas: %s", rd.Name, currentDesiredReplicas, newDesiredReplicas, reason)
+
+		log.Info("Scaled runnerdeployment", "runner_deployment", rd.Name, "replicas_before", currentDesiredReplicas, "replicas", newDesiredReplicas, "reason", reason)
 
-	if hra.Status.DesiredReplicas == nil || *hra.Status.DesiredReplicas != newDesiredReplicas {
-		updated = hra.DeepCopy()
+		updated.Status.LastScaleTime = &metav1.Time{Time: now}
+		updated.Status.LastScaleReason = reason
 
+		setHorizontalRunnerAutoscalerCondition(updated, v1alpha1.AbleToScale, corev1.ConditionTrue, "SucceededRescale", fmt.Sprintf("the runnerdeployment was scaled from %d to %d replicas", currentDesiredReplicas, newDesiredReplicas), now)
+	} else if scaleDownDelayed {
+		setHorizontalRunnerAutoscalerCondition(updated, v1alpha1.AbleToScale, corev1.ConditionTrue, "ScaleDownStabilized", "scaling down is delayed until scaleDownDelaySecondsAfterScaleOut passes after the last scale out", now)
+	} else if replicasFromCache == nil {
+		setHorizontalRunnerAutoscalerCondition(updated, v1alpha1.AbleToScale, corev1.ConditionTrue, "ReadyForNewScale", "the runnerdeployment is ready to be scaled", now)
+	}
+
+	// The desired replicas are recorded only when they are applied, so that the scale down delay keeps working once resumed
+	if !hra.Spec.Paused && (hra.Status.DesiredReplicas == nil || *hra.Status.DesiredReplicas != newDesiredReplicas) {
 		if (hra.Status.DesiredReplicas == nil && newDesiredReplicas > 1) ||
 			(hra.Status.DesiredReplicas != nil && newDesiredReplicas > *hra.Status.DesiredReplicas) {
 
@@ -144,10 +253,6 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(req ctrl.Request) (ctrl
 	}
 
 	if replicasFromCache == nil {
-		if updated == nil {
-			updated = hra.DeepCopy()
-		}
-
 		cacheEntries := getValidCacheEntries(updated, now)
 
 		var cacheDuration time.Duration
@@ -163,17 +268,43 @@ func (r *HorizontalRunnerAutoscalerReconciler) Reconcile(req ctrl.Request) (ctrl
 			Value:          *replicas,
 			ExpirationTime: metav1.Time{Time: time.Now().Add(cacheDuration)},
 		})
+
+		updated.Status.CurrentMetrics = metricStatuses
 	}
 
-	if updated != nil {
+	if !equality.Semantic.DeepEqual(hra.Status, updated.Status) {
 		if err := r.Status().Patch(ctx, updated, client.MergeFrom(&hra)); err != nil {
 			return ctrl.Result{}, fmt.Errorf("patching horizontalrunnerautoscaler status to add cache entry: %w", err)
 		}
 	}
 
+	metrics.SetHorizontalRunnerAutoscalerReplicas(hra.Namespace, hra.Name, rd.Name, autoscaler.Spec.MinReplicas, autoscaler.Spec.MaxReplicas, newDesiredReplicas)
+
+	for _, m := range metricStatuses {
+		metrics.SetHorizontalRunnerAutoscalerMetric(hra.Namespace, hra.Name, rd.Name, m.Type, m.CurrentValue, m.DesiredReplicas)
+	}
+
+	if nextScheduledOverrideTransition != nil {
+		return ctrl.Result{RequeueAfter: nextScheduledOverrideTransition.Sub(now)}, nil
+	}
+
 	return ctrl.Result{}, nil
 }
 
+// patchStatusOnError patches the status of the autoscaler to record the conditions describing the error,
+// and returns the error so that the reconciliation is retried.
+func (r *HorizontalRunnerAutoscalerReconciler) patchStatusOnError(ctx context.Context, hra, updated *v1alpha1.HorizontalRunnerAutoscaler, err error) error {
+	if equality.Semantic.DeepEqual(hra.Status, updated.Status) {
+		return err
+	}
+
+	if patchErr := r.Status().Patch(ctx, updated, client.MergeFrom(hra)); patchErr != nil {
+		r.Log.Error(patchErr, "Could not patch horizontalrunnerautoscaler status", "horizontalrunnerautoscaler", types.NamespacedName{Namespace: hra.Namespace, Name: hra.Name})
+	}
+
+	return err
+}
+
 func getValidCacheEntries(hra *v1alpha1.HorizontalRunnerAutoscaler, now time.Time) []v1alpha1.CacheEntry {
 	var cacheEntries []v1alpha1.CacheEntry
 
@@ -200,12 +331,14 @@ func (r *HorizontalRunnerAutoscalerReconciler) SetupWithManager(mgr ctrl.Manager
 		Complete(r)
 }
 
-func (r *HorizontalRunnerAutoscalerReconciler) computeReplicas(rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, error) {
+// computeReplicas returns the desired replicas computed from the metrics, along with the desired replicas per metric.
+// It also returns true when the desired replicas is held back from scaling down by scaleDownDelaySecondsAfterScaleOut.
+func (r *HorizontalRunnerAutoscalerReconciler) computeReplicas(ghc *github.Client, rd v1alpha1.RunnerDeployment, hra v1alpha1.HorizontalRunnerAutoscaler) (*int, []v1alpha1.MetricStatus, bool, error) {
 	var computedReplicas *int
 
-	replicas, err := r.determineDesiredReplicas(rd, hra)
+	replicas, metricStatuses, err := r.determineDesiredReplicas(ghc, rd, hra)
 	if err != nil {
-		return nil, err
+		return nil, nil, false, err
 	}
 
 	var scaleDownDelay time.Duration
@@ -228,5 +361,7 @@ func (r *HorizontalRunnerAutoscalerReconciler) computeReplicas(rd v1alpha1.Runne
 		computedReplicas = hra.Status.DesiredReplicas
 	}
 
-	return computedReplicas, nil
+	scaleDownDelayed := *computedReplicas > *replicas
+
+	return computedReplicas, metricStatuses, scaleDownDelayed, nil
 }
@@ -1,11 +1,22 @@
 package controllers
 
 import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
 	"github.com/google/go-cmp/cmp"
 	actionsv1alpha1 "github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"testing"
-	"time"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	kfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
 
 func TestGetValidCacheEntries(t *testing.T) {
@@ -47,3 +58,207 @@ func TestGetValidCacheEntries(t *testing.T) {
 		t.Errorf("%s", d)
 	}
 }
+
+func TestReconcile_StatusAndEvents(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	testcases := []struct {
+		name         string
+		replicas     int
+		min          int
+		max          int
+		reservations []actionsv1alpha1.CapacityReservation
+		paused       bool
+		want         int
+		wantLimited  string
+		wantReason   string
+		wantEvent    string
+	}{
+		{
+			name:        "scale up to min",
+			replicas:    1,
+			min:         2,
+			max:         5,
+			want:        2,
+			wantLimited: "DesiredWithinRange",
+			wantReason:  "2 replicas computed",
+			wantEvent:   "Normal SuccessfulRescale Scaled runnerdeployment testrd from 1 to 2 replicas: 2 replicas computed",
+		},
+		{
+			name:     "scale up by capacity reservations",
+			replicas: 1,
+			min:      1,
+			max:      5,
+			reservations: []actionsv1alpha1.CapacityReservation{
+				{Replicas: 2, ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}},
+			},
+			want:        3,
+			wantLimited: "DesiredWithinRange",
+			wantReason:  "1 replicas computed, 2 replicas added by capacity reservations",
+			wantEvent:   "Normal SuccessfulRescale Scaled runnerdeployment testrd from 1 to 3 replicas: 1 replicas computed, 2 replicas added by capacity reservations",
+		},
+		{
+			name:     "capacity reservations limited by max",
+			replicas: 1,
+			min:      1,
+			max:      2,
+			reservations: []actionsv1alpha1.CapacityReservation{
+				{Replicas: 3, ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}},
+			},
+			want:        2,
+			wantLimited: "TooManyReplicas",
+			wantReason:  "1 replicas computed, 3 replicas added by capacity reservations, limited by maxReplicas 2",
+			wantEvent:   "Normal SuccessfulRescale Scaled runnerdeployment testrd from 1 to 2 replicas: 1 replicas computed, 3 replicas added by capacity reservations, limited by maxReplicas 2",
+		},
+		{
+			name:        "paused",
+			replicas:    1,
+			min:         2,
+			max:         5,
+			paused:      true,
+			want:        1,
+			wantLimited: "DesiredWithinRange",
+		},
+		{
+			name:     "capacity reservations up to max",
+			replicas: 1,
+			min:      1,
+			max:      3,
+			reservations: []actionsv1alpha1.CapacityReservation{
+				{Replicas: 2, ExpirationTime: metav1.Time{Time: time.Now().Add(time.Hour)}},
+			},
+			want:        3,
+			wantLimited: "DesiredWithinRange",
+			wantReason:  "1 replicas computed, 2 replicas added by capacity reservations",
+			wantEvent:   "Normal SuccessfulRescale Scaled runnerdeployment testrd from 1 to 3 replicas: 1 replicas computed, 2 replicas added by capacity reservations",
+		},
+		{
+			name:        "no change",
+			replicas:    2,
+			min:         2,
+			max:         5,
+			want:        2,
+			wantLimited: "DesiredWithinRange",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			scheme := runtime.NewScheme()
+			_ = clientgoscheme.AddToScheme(scheme)
+			_ = actionsv1alpha1.AddToScheme(scheme)
+
+			rd := &actionsv1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testrd",
+					Namespace: "default",
+				},
+				Spec: actionsv1alpha1.RunnerDeploymentSpec{
+					Replicas: intPtr(tc.replicas),
+				},
+			}
+
+			hra := &actionsv1alpha1.HorizontalRunnerAutoscaler{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "testhra",
+					Namespace: "default",
+				},
+				Spec: actionsv1alpha1.HorizontalRunnerAutoscalerSpec{
+					ScaleTargetRef: actionsv1alpha1.ScaleTargetRef{
+						Name: "testrd",
+					},
+					MinReplicas: intPtr(tc.min),
+					MaxReplicas: intPtr(tc.max),
+					ScaleUpTriggers: []actionsv1alpha1.ScaleUpTrigger{
+						{GitHubEvent: &actionsv1alpha1.GitHubEventScaleUpTriggerSpec{}},
+					},
+					CapacityReservations: tc.reservations,
+					Paused:               tc.paused,
+				},
+			}
+
+			recorder := record.NewFakeRecorder(10)
+
+			client := kfake.NewFakeClientWithScheme(scheme, rd, hra)
+
+			r := &HorizontalRunnerAutoscalerReconciler{
+				Client:       client,
+				Log:          zap.New(),
+				Recorder:     recorder,
+				Scheme:       scheme,
+				GitHubClient: NewMultiGitHubClient(client, nil, github.Config{}),
+			}
+
+			key := types.NamespacedName{Namespace: "default", Name: "testhra"}
+
+			if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var updatedRD actionsv1alpha1.RunnerDeployment
+			if err := r.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: "testrd"}, &updatedRD); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			if *updatedRD.Spec.Replicas != tc.want {
+				t.Errorf("unexpected replicas: want %d, got %d", tc.want, *updatedRD.Spec.Replicas)
+			}
+
+			var updated actionsv1alpha1.HorizontalRunnerAutoscaler
+			if err := r.Get(context.Background(), key, &updated); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			conditions := map[actionsv1alpha1.HorizontalRunnerAutoscalerConditionType]string{}
+			for _, c := range updated.Status.Conditions {
+				conditions[c.Type] = fmt.Sprintf("%s/%s", c.Status, c.Reason)
+			}
+
+			wantAbleToScale := "True/ReadyForNewScale"
+			if tc.wantEvent != "" {
+				wantAbleToScale = "True/SucceededRescale"
+			} else if tc.paused {
+				wantAbleToScale = "False/ScalingPaused"
+			}
+
+			wantLimitedStatus := "True"
+			if tc.wantLimited == "DesiredWithinRange" {
+				wantLimitedStatus = "False"
+			}
+
+			wantConditions := map[actionsv1alpha1.HorizontalRunnerAutoscalerConditionType]string{
+				actionsv1alpha1.AbleToScale:    wantAbleToScale,
+				actionsv1alpha1.ScalingActive:  "True/ValidMetricFound",
+				actionsv1alpha1.ScalingLimited: wantLimitedStatus + "/" + tc.wantLimited,
+			}
+
+			if d := cmp.Diff(wantConditions, conditions); d != "" {
+				t.Errorf("unexpected conditions: %s", d)
+			}
+
+			if tc.paused && updated.Status.DesiredReplicas != nil {
+				t.Errorf("unexpected desired replicas while paused: want none, got %d", *updated.Status.DesiredReplicas)
+			}
+
+			if updated.Status.LastScaleReason != tc.wantReason {
+				t.Errorf("unexpected last scale reason: want %q, got %q", tc.wantReason, updated.Status.LastScaleReason)
+			}
+
+			var events []string
+			for len(recorder.Events) > 0 {
+				events = append(events, <-recorder.Events)
+			}
+
+			var wantEvents []string
+			if tc.wantEvent != "" {
+				wantEvents = append(wantEvents, tc.wantEvent)
+			}
+
+			if d := cmp.Diff(wantEvents, events); d != "" {
+				t.Errorf("unexpected events: %s", d)
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package controllers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// setHorizontalRunnerAutoscalerCondition adds or updates the condition of the type.
+// The last transition time is updated only when the status of the condition changes.
+func setHorizontalRunnerAutoscalerCondition(hra *v1alpha1.HorizontalRunnerAutoscaler, conditionType v1alpha1.HorizontalRunnerAutoscalerConditionType, status corev1.ConditionStatus, reason, message string, now time.Time) {
+	for i := range hra.Status.Conditions {
+		c := &hra.Status.Conditions[i]
+
+		if c.Type != conditionType {
+			continue
+		}
+
+		if c.Status != status {
+			c.Status = status
+			c.LastTransitionTime = metav1.Time{Time: now}
+		}
+
+		c.Reason = reason
+		c.Message = message
+
+		return
+	}
+
+	hra.Status.Conditions = append(hra.Status.Conditions, v1alpha1.HorizontalRunnerAutoscalerCondition{
+		Type:               conditionType,
+		Status:             status,
+		LastTransitionTime: metav1.Time{Time: now},
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+// describeScale explains how the autoscaler came up with the new desired replicas, so that
+// one can tell why the scale target has the number of replicas without raising the log verbosity.
+func describeScale(cached bool, replicas int, metrics []v1alpha1.MetricStatus, policy string, scaleDownDelayed bool, reservedReplicas int, limited string, override *v1alpha1.ScheduledOverride) string {
+	var reasons []string
+
+	if cached {
+		reasons = append(reasons, fmt.Sprintf("%d replicas cached from the last computation", replicas))
+	} else if scaleDownDelayed {
+		reasons = append(reasons, fmt.Sprintf("%d replicas kept until scaleDownDelaySecondsAfterScaleOut passes after the last scale out", replicas))
+	} else if len(metrics) > 0 {
+		if policy == "" {
+			policy = v1alpha1.MetricsPolicyMax
+		}
+
+		var ms []string
+
+		for _, m := range metrics {
+			if m.CurrentValue != "" {
+				ms = append(ms, fmt.Sprintf("%s=%s wants %d", m.Type, m.CurrentValue, m.DesiredReplicas))
+			} else {
+				ms = append(ms, fmt.Sprintf("%s wants %d", m.Type, m.DesiredReplicas))
+			}
+		}
+
+		reasons = append(reasons, fmt.Sprintf("%d replicas computed from metrics by %s policy (%s)", replicas, policy, strings.Join(ms, ", ")))
+	} else {
+		reasons = append(reasons, fmt.Sprintf("%d replicas computed", replicas))
+	}
+
+	if reservedReplicas > 0 {
+		reasons = append(reasons, fmt.Sprintf("%d replicas added by capacity reservations", reservedReplicas))
+	}
+
+	if limited != "" {
+		reasons = append(reasons, limited)
+	}
+
+	if override != nil {
+		tz := override.TimeZone
+		if tz == "" {
+			tz = "UTC"
+		}
+
+		reasons = append(reasons, fmt.Sprintf("scheduled override %q in %s is active", override.Schedule, tz))
+	}
+
+	return strings.Join(reasons, ", ")
+}
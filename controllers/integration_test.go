@@ -102,7 +102,7 @@ func SetupIntegrationTest(ctx context.Context) *testEnvironment {
 			Scheme:       scheme.Scheme,
 			Log:          logf.Log,
 			Recorder:     mgr.GetEventRecorderFor("runnerreplicaset-controller"),
-			GitHubClient: env.ghClient,
+			GitHubClient: NewMultiGitHubClient(mgr.GetClient(), env.ghClient, github2.Config{}),
 			Name:         controllerName("runnerreplicaset"),
 		}
 		err = replicasetController.SetupWithManager(mgr)
@@ -122,7 +122,7 @@ func SetupIntegrationTest(ctx context.Context) *testEnvironment {
 			Client:        mgr.GetClient(),
 			Scheme:        scheme.Scheme,
 			Log:           logf.Log,
-			GitHubClient:  env.ghClient,
+			GitHubClient:  NewMultiGitHubClient(mgr.GetClient(), env.ghClient, github2.Config{}),
 			Recorder:      mgr.GetEventRecorderFor("horizontalrunnerautoscaler-controller"),
 			CacheDuration: 1 * time.Second,
 			Name:          controllerName("horizontalrunnerautoscaler"),
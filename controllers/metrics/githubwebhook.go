@@ -0,0 +1,48 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	webhookEvent = "event"
+)
+
+var (
+	githubWebhookEventsReceived = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_webhook_events_received_total",
+			Help: "The number of GitHub webhook events received by the webhook-based autoscaler",
+		},
+		[]string{webhookEvent},
+	)
+	githubWebhookEventsMatched = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_webhook_events_matched_total",
+			Help: "The number of GitHub webhook events that matched a scale trigger and resulted in scaling a horizontalrunnerautoscaler",
+		},
+		[]string{webhookEvent},
+	)
+	githubWebhookEventsIgnored = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_webhook_events_ignored_total",
+			Help: "The number of GitHub webhook events that didn't match any scale trigger",
+		},
+		[]string{webhookEvent},
+	)
+)
+
+// IncGitHubWebhookEventsReceived counts a GitHub webhook event of the type like `check_run` received.
+func IncGitHubWebhookEventsReceived(event string) {
+	githubWebhookEventsReceived.With(prometheus.Labels{webhookEvent: event}).Inc()
+}
+
+// IncGitHubWebhookEventsMatched counts a GitHub webhook event that resulted in scaling.
+func IncGitHubWebhookEventsMatched(event string) {
+	githubWebhookEventsMatched.With(prometheus.Labels{webhookEvent: event}).Inc()
+}
+
+// IncGitHubWebhookEventsIgnored counts a GitHub webhook event that didn't result in scaling.
+func IncGitHubWebhookEventsIgnored(event string) {
+	githubWebhookEventsIgnored.With(prometheus.Labels{webhookEvent: event}).Inc()
+}
@@ -0,0 +1,128 @@
+package metrics
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	hraName             = "horizontalrunnerautoscaler"
+	hraNamespace        = "namespace"
+	hraRunnerDeployment = "runnerdeployment"
+	hraMetricType       = "metric_type"
+)
+
+var (
+	horizontalRunnerAutoscalerMinReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_min_replicas",
+			Help: "minReplicas of the horizontalrunnerautoscaler, including the one of the active scheduled override",
+		},
+		[]string{hraName, hraNamespace, hraRunnerDeployment},
+	)
+	horizontalRunnerAutoscalerMaxReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_max_replicas",
+			Help: "maxReplicas of the horizontalrunnerautoscaler, including the one of the active scheduled override",
+		},
+		[]string{hraName, hraNamespace, hraRunnerDeployment},
+	)
+	horizontalRunnerAutoscalerDesiredReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_desired_replicas",
+			Help: "The number of replicas the horizontalrunnerautoscaler wants the runnerdeployment to have",
+		},
+		[]string{hraName, hraNamespace, hraRunnerDeployment},
+	)
+	horizontalRunnerAutoscalerMetricValue = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_metric_value",
+			Help: "The last observed value of the autoscaling metric",
+		},
+		[]string{hraName, hraNamespace, hraRunnerDeployment, hraMetricType},
+	)
+	horizontalRunnerAutoscalerMetricDesiredReplicas = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "horizontalrunnerautoscaler_metric_desired_replicas",
+			Help: "The number of replicas desired by the autoscaling metric",
+		},
+		[]string{hraName, hraNamespace, hraRunnerDeployment, hraMetricType},
+	)
+)
+
+var (
+	// horizontalRunnerAutoscalerLabels is the label sets exported per horizontalrunnerautoscaler, keyed by namespace/name.
+	// It's used to stop exporting all of them once the horizontalrunnerautoscaler is deleted.
+	horizontalRunnerAutoscalerLabels   = map[string][]prometheus.Labels{}
+	horizontalRunnerAutoscalerLabelsMu sync.Mutex
+)
+
+func trackHorizontalRunnerAutoscalerLabels(namespace, name string, labels prometheus.Labels) {
+	horizontalRunnerAutoscalerLabelsMu.Lock()
+	defer horizontalRunnerAutoscalerLabelsMu.Unlock()
+
+	key := namespace + "/" + name
+
+	for _, l := range horizontalRunnerAutoscalerLabels[key] {
+		if l[hraRunnerDeployment] == labels[hraRunnerDeployment] && l[hraMetricType] == labels[hraMetricType] {
+			return
+		}
+	}
+
+	horizontalRunnerAutoscalerLabels[key] = append(horizontalRunnerAutoscalerLabels[key], labels)
+}
+
+// SetHorizontalRunnerAutoscalerReplicas sets the min, max and desired replicas of the horizontalrunnerautoscaler.
+// min and max are omitted when they're nil.
+func SetHorizontalRunnerAutoscalerReplicas(namespace, name, runnerDeployment string, min, max *int, desired int) {
+	labels := prometheus.Labels{hraName: name, hraNamespace: namespace, hraRunnerDeployment: runnerDeployment}
+
+	trackHorizontalRunnerAutoscalerLabels(namespace, name, labels)
+
+	if min != nil {
+		horizontalRunnerAutoscalerMinReplicas.With(labels).Set(float64(*min))
+	}
+
+	if max != nil {
+		horizontalRunnerAutoscalerMaxReplicas.With(labels).Set(float64(*max))
+	}
+
+	horizontalRunnerAutoscalerDesiredReplicas.With(labels).Set(float64(desired))
+}
+
+// SetHorizontalRunnerAutoscalerMetric sets the last observed value and the desired replicas of the autoscaling metric.
+// The value is omitted when it isn't a number, which is the case when the metric couldn't be observed.
+func SetHorizontalRunnerAutoscalerMetric(namespace, name, runnerDeployment, metricType, value string, desiredReplicas int) {
+	labels := prometheus.Labels{hraName: name, hraNamespace: namespace, hraRunnerDeployment: runnerDeployment, hraMetricType: metricType}
+
+	trackHorizontalRunnerAutoscalerLabels(namespace, name, labels)
+
+	if v, err := strconv.ParseFloat(value, 64); err == nil {
+		horizontalRunnerAutoscalerMetricValue.With(labels).Set(v)
+	}
+
+	horizontalRunnerAutoscalerMetricDesiredReplicas.With(labels).Set(float64(desiredReplicas))
+}
+
+// DeleteHorizontalRunnerAutoscaler stops exporting the metrics of the deleted horizontalrunnerautoscaler.
+func DeleteHorizontalRunnerAutoscaler(namespace, name string) {
+	horizontalRunnerAutoscalerLabelsMu.Lock()
+	defer horizontalRunnerAutoscalerLabelsMu.Unlock()
+
+	key := namespace + "/" + name
+
+	for _, labels := range horizontalRunnerAutoscalerLabels[key] {
+		if _, ok := labels[hraMetricType]; ok {
+			horizontalRunnerAutoscalerMetricValue.Delete(labels)
+			horizontalRunnerAutoscalerMetricDesiredReplicas.Delete(labels)
+		} else {
+			horizontalRunnerAutoscalerMinReplicas.Delete(labels)
+			horizontalRunnerAutoscalerMaxReplicas.Delete(labels)
+			horizontalRunnerAutoscalerDesiredReplicas.Delete(labels)
+		}
+	}
+
+	delete(horizontalRunnerAutoscalerLabels, key)
+}
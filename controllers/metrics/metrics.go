@@ -0,0 +1,27 @@
+// Package metrics provides monitoring of the runners, runner deployments and autoscalers managed by the controllers.
+//
+// This depends on the metrics exporter of kubebuilder.
+// See https://book.kubebuilder.io/reference/metrics.html for details.
+package metrics
+
+import (
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		runnerDeploymentRunners,
+		runnerDeploymentBusyRunners,
+		runnerDeploymentIdleRunners,
+		horizontalRunnerAutoscalerMinReplicas,
+		horizontalRunnerAutoscalerMaxReplicas,
+		horizontalRunnerAutoscalerDesiredReplicas,
+		horizontalRunnerAutoscalerMetricValue,
+		horizontalRunnerAutoscalerMetricDesiredReplicas,
+		githubWebhookEventsReceived,
+		githubWebhookEventsMatched,
+		githubWebhookEventsIgnored,
+		runnerRegistrationTokenFailures,
+		runnerRegistrationTimeouts,
+	)
+}
@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestSetRunnerDeploymentRunners(t *testing.T) {
+	SetRunnerDeploymentRunners("default", "example", map[string]int{
+		"":        1,
+		"Running": 2,
+		"Created": 3,
+	})
+
+	want := map[string]float64{
+		"Unknown":   4,
+		"Pending":   0,
+		"Running":   2,
+		"Succeeded": 0,
+		"Failed":    0,
+	}
+
+	for phase, n := range want {
+		got := testutil.ToFloat64(runnerDeploymentRunners.With(prometheus.Labels{rdName: "example", rdNamespace: "default", rdPhase: phase}))
+		if got != n {
+			t.Errorf("unexpected number of %s runners: want %v, got %v", phase, n, got)
+		}
+	}
+
+	DeleteRunnerDeployment("default", "example")
+
+	if n := collectAndCount(runnerDeploymentRunners); n != 0 {
+		t.Errorf("unexpected number of runnerdeployment_runners series after deletion: want 0, got %d", n)
+	}
+}
+
+func TestDeleteHorizontalRunnerAutoscaler(t *testing.T) {
+	min, max := 1, 10
+
+	SetHorizontalRunnerAutoscalerReplicas("default", "example", "example-rd", &min, &max, 3)
+	SetHorizontalRunnerAutoscalerMetric("default", "example", "example-rd", "PercentageRunnersBusy", "0.75", 3)
+	SetHorizontalRunnerAutoscalerMetric("default", "example", "example-rd", "TotalNumberOfQueuedAndInProgressWorkflowRuns", "", 2)
+	SetHorizontalRunnerAutoscalerReplicas("default", "other", "other-rd", nil, nil, 1)
+
+	if got := testutil.ToFloat64(horizontalRunnerAutoscalerMetricValue.With(prometheus.Labels{hraName: "example", hraNamespace: "default", hraRunnerDeployment: "example-rd", hraMetricType: "PercentageRunnersBusy"})); got != 0.75 {
+		t.Errorf("unexpected metric value: want 0.75, got %v", got)
+	}
+
+	DeleteHorizontalRunnerAutoscaler("default", "example")
+
+	for name, c := range map[string]prometheus.Collector{
+		"horizontalrunnerautoscaler_min_replicas":            horizontalRunnerAutoscalerMinReplicas,
+		"horizontalrunnerautoscaler_max_replicas":            horizontalRunnerAutoscalerMaxReplicas,
+		"horizontalrunnerautoscaler_metric_value":            horizontalRunnerAutoscalerMetricValue,
+		"horizontalrunnerautoscaler_metric_desired_replicas": horizontalRunnerAutoscalerMetricDesiredReplicas,
+	} {
+		if n := collectAndCount(c); n != 0 {
+			t.Errorf("unexpected number of %s series after deletion: want 0, got %d", name, n)
+		}
+	}
+
+	if n := collectAndCount(horizontalRunnerAutoscalerDesiredReplicas); n != 1 {
+		t.Errorf("unexpected number of horizontalrunnerautoscaler_desired_replicas series after deletion: want 1, got %d", n)
+	}
+
+	DeleteHorizontalRunnerAutoscaler("default", "other")
+}
+
+func collectAndCount(c prometheus.Collector) int {
+	ch := make(chan prometheus.Metric)
+
+	go func() {
+		c.Collect(ch)
+		close(ch)
+	}()
+
+	var n int
+
+	for range ch {
+		n++
+	}
+
+	return n
+}
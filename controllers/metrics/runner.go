@@ -0,0 +1,43 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	runnerNamespace = "namespace"
+	runnerAction    = "action"
+
+	// RegistrationTimeoutActionRecreatePod is the action taken by the runner controller on a registration timeout.
+	RegistrationTimeoutActionRecreatePod = "recreate_pod"
+
+	// RegistrationTimeoutActionDeleteRunner is the action taken by the runnerreplicaset controller on a registration timeout.
+	RegistrationTimeoutActionDeleteRunner = "delete_runner"
+)
+
+var (
+	runnerRegistrationTokenFailures = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "runner_registration_token_failures_total",
+			Help: "The number of times the controller failed to get a registration token for a runner",
+		},
+		[]string{runnerNamespace},
+	)
+	runnerRegistrationTimeouts = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "runner_registration_timeouts_total",
+			Help: "The number of times a runner failed to register itself to GitHub in time, per the action taken for it",
+		},
+		[]string{runnerNamespace, runnerAction},
+	)
+)
+
+// IncRunnerRegistrationTokenFailures counts a failure to get a registration token for a runner in the namespace.
+func IncRunnerRegistrationTokenFailures(namespace string) {
+	runnerRegistrationTokenFailures.With(prometheus.Labels{runnerNamespace: namespace}).Inc()
+}
+
+// IncRunnerRegistrationTimeouts counts a runner in the namespace that failed to register itself to GitHub in time.
+func IncRunnerRegistrationTimeouts(namespace, action string) {
+	runnerRegistrationTimeouts.With(prometheus.Labels{runnerNamespace: namespace, runnerAction: action}).Inc()
+}
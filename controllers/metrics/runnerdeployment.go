@@ -0,0 +1,86 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+const (
+	rdName      = "runnerdeployment"
+	rdNamespace = "namespace"
+	rdPhase     = "phase"
+)
+
+var (
+	runnerDeploymentRunners = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "runnerdeployment_runners",
+			Help: "The number of runners of the runnerdeployment per runner phase",
+		},
+		[]string{rdName, rdNamespace, rdPhase},
+	)
+	runnerDeploymentBusyRunners = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "runnerdeployment_busy_runners",
+			Help: "The number of runners of the runnerdeployment that are running a job. Updated only while the PercentageRunnersBusy metric is used",
+		},
+		[]string{rdName, rdNamespace},
+	)
+	runnerDeploymentIdleRunners = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "runnerdeployment_idle_runners",
+			Help: "The number of registered runners of the runnerdeployment that aren't running a job. Updated only while the PercentageRunnersBusy metric is used",
+		},
+		[]string{rdName, rdNamespace},
+	)
+)
+
+// RunnerPhases is the list of runner phases exported as runnerdeployment_runners.
+// Runners in any other phase, including the ones without phase, are counted as Unknown.
+var RunnerPhases = []string{"Unknown", "Pending", "Running", "Succeeded", "Failed", "Draining"}
+
+// SetRunnerDeploymentRunners sets the number of runners of the runnerdeployment per runner phase.
+func SetRunnerDeploymentRunners(namespace, name string, phases map[string]int) {
+	counts := map[string]int{}
+
+	for phase, n := range phases {
+		if !isKnownRunnerPhase(phase) {
+			phase = "Unknown"
+		}
+
+		counts[phase] += n
+	}
+
+	for _, phase := range RunnerPhases {
+		runnerDeploymentRunners.With(prometheus.Labels{rdName: name, rdNamespace: namespace, rdPhase: phase}).Set(float64(counts[phase]))
+	}
+}
+
+func isKnownRunnerPhase(phase string) bool {
+	for _, p := range RunnerPhases {
+		if p == phase {
+			return true
+		}
+	}
+
+	return false
+}
+
+// SetRunnerDeploymentBusyRunners sets the number of busy and idle runners among the registered ones.
+func SetRunnerDeploymentBusyRunners(namespace, name string, busy, registered int) {
+	labels := prometheus.Labels{rdName: name, rdNamespace: namespace}
+
+	runnerDeploymentBusyRunners.With(labels).Set(float64(busy))
+	runnerDeploymentIdleRunners.With(labels).Set(float64(registered - busy))
+}
+
+// DeleteRunnerDeployment stops exporting the metrics of the deleted runnerdeployment.
+func DeleteRunnerDeployment(namespace, name string) {
+	for _, phase := range RunnerPhases {
+		runnerDeploymentRunners.Delete(prometheus.Labels{rdName: name, rdNamespace: namespace, rdPhase: phase})
+	}
+
+	labels := prometheus.Labels{rdName: name, rdNamespace: namespace}
+
+	runnerDeploymentBusyRunners.Delete(labels)
+	runnerDeploymentIdleRunners.Delete(labels)
+}
@@ -0,0 +1,202 @@
+package controllers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-logr/logr"
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
+	"github.com/summerwind/actions-runner-controller/hash"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// Keys of the secret referenced by githubAPICredentialsFrom.
+	// They are the same as the ones of the secret of the controller.
+	secretKeyGitHubToken             = "github_token"
+	secretKeyGitHubAppID             = "github_app_id"
+	secretKeyGitHubAppInstallationID = "github_app_installation_id"
+	secretKeyGitHubAppPrivateKey     = "github_app_private_key"
+	secretKeyGitHubEnterpriseURL     = "github_enterprise_url"
+)
+
+// MultiGitHubClient is the registry of GitHub clients shared by the reconcilers.
+// It returns the client for the GitHub API credentials referenced by a runner,
+// or the default client of the controller when the runner doesn't reference any.
+type MultiGitHubClient struct {
+	client client.Client
+	log    logr.Logger
+
+	githubClient *github.Client
+	config       github.Config
+
+	mu      sync.Mutex
+	clients map[types.NamespacedName]multiGitHubClientEntry
+}
+
+type multiGitHubClientEntry struct {
+	// secretHash is the hash of the secret data the client was created from,
+	// so that the client is recreated once the credentials are rotated.
+	secretHash string
+	client     *github.Client
+}
+
+// NewMultiGitHubClient creates a MultiGitHubClient that falls back to githubClient created from config.
+// config is also used as the base of the clients created from the secrets, so that they share settings
+// like the runner list cache duration and the enterprise URL.
+func NewMultiGitHubClient(client client.Client, githubClient *github.Client, config github.Config) *MultiGitHubClient {
+	return &MultiGitHubClient{
+		client:       client,
+		log:          ctrl.Log.WithName("githubclient"),
+		githubClient: githubClient,
+		config:       config,
+		clients:      map[types.NamespacedName]multiGitHubClientEntry{},
+	}
+}
+
+// InitForRunner returns the GitHub client to manage the runner with.
+func (c *MultiGitHubClient) InitForRunner(ctx context.Context, runner *v1alpha1.Runner) (*github.Client, error) {
+	return c.Init(ctx, runner.Namespace, runner.Spec.GitHubAPICredentialsFrom)
+}
+
+// Init returns the GitHub client for the credentials stored in the secret in the namespace,
+// or the default client when no credentials are referenced.
+func (c *MultiGitHubClient) Init(ctx context.Context, namespace string, from *v1alpha1.GitHubAPICredentialsFrom) (*github.Client, error) {
+	if from == nil {
+		return c.githubClient, nil
+	}
+
+	key := types.NamespacedName{Namespace: namespace, Name: from.SecretRef.Name}
+
+	var secret corev1.Secret
+	if err := c.client.Get(ctx, key, &secret); err != nil {
+		return nil, fmt.Errorf("getting secret %s for github api credentials: %w", key, err)
+	}
+
+	secretHash := hash.FNVHashStringObjects(secret.Data)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.clients[key]; ok && e.secretHash == secretHash {
+		return e.client, nil
+	}
+
+	conf, err := secretDataToGitHubConfig(secret.Data, c.config)
+	if err != nil {
+		return nil, fmt.Errorf("reading github api credentials from secret %s: %w", key, err)
+	}
+
+	ghc, err := conf.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("creating github client from secret %s: %w", key, err)
+	}
+
+	c.clients[key] = multiGitHubClientEntry{
+		secretHash: secretHash,
+		client:     ghc,
+	}
+
+	return ghc, nil
+}
+
+// isSecretNotFound returns true when err is caused by the missing secret referenced by githubAPICredentialsFrom.
+func isSecretNotFound(err error) bool {
+	var statusErr *kerrors.StatusError
+
+	return errors.As(err, &statusErr) && kerrors.IsNotFound(statusErr)
+}
+
+// Start keeps the registration tokens of all the clients valid ahead of their expiration until stop is closed,
+// so that runner pods can be created without waiting for a token to be created.
+// It implements manager.Runnable.
+func (c *MultiGitHubClient) Start(stop <-chan struct{}) error {
+	ticker := time.NewTicker(github.RegistrationTokenRefreshInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return nil
+		case <-ticker.C:
+			c.refreshRegistrationTokens()
+		}
+	}
+}
+
+func (c *MultiGitHubClient) refreshRegistrationTokens() {
+	var clients []*github.Client
+
+	if c.githubClient != nil {
+		clients = append(clients, c.githubClient)
+	}
+
+	c.mu.Lock()
+	for _, e := range c.clients {
+		clients = append(clients, e.client)
+	}
+	c.mu.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), github.RegistrationTokenRefreshInterval)
+	defer cancel()
+
+	for _, ghc := range clients {
+		if err := ghc.RefreshRegistrationTokens(ctx); err != nil {
+			c.log.Error(err, "Failed to refresh registration tokens")
+		}
+	}
+}
+
+func secretDataToGitHubConfig(data map[string][]byte, base github.Config) (*github.Config, error) {
+	conf := github.Config{
+		EnterpriseURL:           base.EnterpriseURL,
+		RunnerListCacheDuration: base.RunnerListCacheDuration,
+		RateLimitReserve:        base.RateLimitReserve,
+		ConditionalRequests:     base.ConditionalRequests,
+	}
+
+	if v, ok := data[secretKeyGitHubEnterpriseURL]; ok {
+		conf.EnterpriseURL = strings.TrimSpace(string(v))
+	}
+
+	if v, ok := data[secretKeyGitHubToken]; ok {
+		conf.Token = strings.TrimSpace(string(v))
+
+		return &conf, nil
+	}
+
+	appID, err := strconv.ParseInt(strings.TrimSpace(string(data[secretKeyGitHubAppID])), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("either %s or valid %s is required: %w", secretKeyGitHubToken, secretKeyGitHubAppID, err)
+	}
+
+	// The installation is looked up per organization or repository when the installation ID is omitted
+	var installationID int64
+	if v, ok := data[secretKeyGitHubAppInstallationID]; ok {
+		installationID, err = strconv.ParseInt(strings.TrimSpace(string(v)), 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %w", secretKeyGitHubAppInstallationID, err)
+		}
+	}
+
+	privateKey, ok := data[secretKeyGitHubAppPrivateKey]
+	if !ok {
+		return nil, fmt.Errorf("%s is required", secretKeyGitHubAppPrivateKey)
+	}
+
+	conf.AppID = appID
+	conf.AppInstallationID = installationID
+	conf.AppPrivateKey = string(privateKey)
+
+	return &conf, nil
+}
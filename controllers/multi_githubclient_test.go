@@ -0,0 +1,96 @@
+package controllers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	kfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestMultiGitHubClient(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "org1-github",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"github_token": []byte("token1\n"),
+		},
+	}
+
+	invalid := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "invalid-github",
+			Namespace: "default",
+		},
+		Data: map[string][]byte{
+			"github_app_id": []byte("1234"),
+		},
+	}
+
+	client := kfake.NewFakeClientWithScheme(scheme, secret, invalid)
+
+	defaultClient := &github.Client{}
+
+	c := NewMultiGitHubClient(client, defaultClient, github.Config{})
+
+	ctx := context.Background()
+
+	got, err := c.Init(ctx, "default", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != defaultClient {
+		t.Errorf("expected the default client for no credentials")
+	}
+
+	from := &v1alpha1.GitHubAPICredentialsFrom{SecretRef: v1alpha1.SecretReference{Name: "org1-github"}}
+
+	first, err := c.Init(ctx, "default", from)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if first == defaultClient {
+		t.Errorf("expected a client for the secret, got the default client")
+	}
+
+	second, err := c.Init(ctx, "default", from)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if second != first {
+		t.Errorf("expected the client for the secret to be reused")
+	}
+
+	secret.Data["github_token"] = []byte("token2")
+	if err := client.Update(ctx, secret); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	rotated, err := c.Init(ctx, "default", from)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rotated == first {
+		t.Errorf("expected the client to be recreated after the secret is updated")
+	}
+
+	if _, err := c.Init(ctx, "other", from); err == nil {
+		t.Errorf("expected error for the secret missing in the namespace, got none")
+	}
+
+	_, err = c.Init(ctx, "default", &v1alpha1.GitHubAPICredentialsFrom{SecretRef: v1alpha1.SecretReference{Name: "invalid-github"}})
+	want := "reading github api credentials from secret default/invalid-github: github_app_private_key is required"
+	if err == nil || err.Error() != want {
+		t.Errorf("unexpected error: want %q, got %v", want, err)
+	}
+}
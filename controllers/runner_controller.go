@@ -20,7 +20,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	gogithub "github.com/google/go-github/v33/github"
 	"github.com/summerwind/actions-runner-controller/hash"
 	"k8s.io/apimachinery/pkg/util/wait"
 	"strings"
@@ -29,6 +28,7 @@ import (
 	"github.com/go-logr/logr"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
@@ -37,16 +37,18 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/controllers/metrics"
 	"github.com/summerwind/actions-runner-controller/github"
 )
 
+// errRunnerBusy is returned when the runner being unregistered is running a job.
+var errRunnerBusy = errors.New("runner is busy")
+
 const (
 	containerName = "runner"
 	finalizerName = "runner.actions.summerwind.dev"
 
 	LabelKeyPodTemplateHash = "pod-template-hash"
-
-	retryDelayOnGitHubAPIRateLimitError = 30 * time.Second
 )
 
 // RunnerReconciler reconciles a Runner object
@@ -55,7 +57,7 @@ type RunnerReconciler struct {
 	Log          logr.Logger
 	Recorder     record.EventRecorder
 	Scheme       *runtime.Scheme
-	GitHubClient *github.Client
+	GitHubClient *MultiGitHubClient
 	RunnerImage  string
 	DockerImage  string
 }
@@ -65,6 +67,7 @@ type RunnerReconciler struct {
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=pods/finalizers,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=core,resources=secrets,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -100,21 +103,30 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		finalizers, removed := removeFinalizer(runner.ObjectMeta.Finalizers)
 
 		if removed {
-			if len(runner.Status.Registration.Token) > 0 {
-				ok, err := r.unregisterRunner(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name)
+			if runner.Status.Registration.ExpiresAt.IsZero() {
+				log.V(1).Info("Runner was never registered on GitHub")
+			} else if ghc, err := r.GitHubClient.InitForRunner(ctx, &runner); err != nil {
+				if !isSecretNotFound(err) {
+					log.Error(err, "Failed to get GitHub client for runner")
+					return ctrl.Result{}, err
+				}
+
+				// Retrying doesn't help once the credentials are gone, and would leave the runner terminating forever.
+				// GitHub removes the runner by itself after it stays offline for a while.
+				log.Error(err, "Skipping removal of runner from GitHub because the GitHub API credentials no longer exist")
+
+				r.Recorder.Event(&runner, corev1.EventTypeWarning, "UnregistrationSkipped", fmt.Sprintf("Skipped removing the runner from GitHub: %v", err))
+			} else {
+				ok, err := r.unregisterRunner(ctx, ghc, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name)
 				if err != nil {
-					if errors.Is(err, &gogithub.RateLimitError{}) {
-						// We log the underlying error when we failed calling GitHub API to list or unregisters,
-						// or the runner is still busy.
-						log.Error(
-							err,
-							fmt.Sprintf(
-								"Failed to unregister runner due to GitHub API rate limits. Delaying retry for %s to avoid excessive GitHub API calls",
-								retryDelayOnGitHubAPIRateLimitError,
-							),
-						)
-
-						return ctrl.Result{RequeueAfter: retryDelayOnGitHubAPIRateLimitError}, err
+					if retryAt, ok := github.RetryAt(err); ok {
+						log.Error(err, "Failed to unregister runner due to GitHub API rate limits. Delaying retry until the rate limit resets", "retryAt", retryAt)
+
+						return ctrl.Result{RequeueAfter: requeueAfter(retryAt)}, nil
+					}
+
+					if errors.Is(err, errRunnerBusy) {
+						return r.drainRunner(ctx, runner)
 					}
 
 					return ctrl.Result{}, err
@@ -123,8 +135,10 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				if !ok {
 					log.V(1).Info("Runner no longer exists on GitHub")
 				}
-			} else {
-				log.V(1).Info("Runner was never registered on GitHub")
+			}
+
+			if runner.Status.Phase == v1alpha1.RunnerPhaseDraining {
+				r.Recorder.Event(&runner, corev1.EventTypeNormal, "RunnerDrained", "Runner has finished the running job and is removed from GitHub")
 			}
 
 			newRunner := runner.DeepCopy()
@@ -141,19 +155,41 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		return ctrl.Result{}, nil
 	}
 
+	if runner.Status.FailureCount >= runner.Spec.GetMaxRegistrationFailures() {
+		log.V(1).Info("Runner has given up registering itself to GitHub. Delete the runner to retry", "failureCount", runner.Status.FailureCount, "reason", runner.Status.Reason)
+		return ctrl.Result{}, nil
+	}
+
 	var pod corev1.Pod
 	if err := r.Get(ctx, req.NamespacedName, &pod); err != nil {
 		if !kerrors.IsNotFound(err) {
 			return ctrl.Result{}, err
 		}
 
-		if updated, err := r.updateRegistrationToken(ctx, runner); err != nil {
+		if n := runner.Status.FailureCount; n > 0 && runner.Status.LastFailureTime != nil {
+			if retryAt := runner.Status.LastFailureTime.Add(runnerFailureBackoff(n)); retryAt.After(time.Now()) {
+				log.Info("Delaying recreation of the runner pod after the failure", "failureCount", n, "reason", runner.Status.Reason, "retryAt", retryAt)
+
+				return ctrl.Result{RequeueAfter: requeueAfter(retryAt)}, nil
+			}
+		}
+
+		ghc, err := r.GitHubClient.InitForRunner(ctx, &runner)
+		if err != nil {
+			log.Error(err, "Failed to get GitHub client for runner")
 			return ctrl.Result{}, err
-		} else if updated {
-			return ctrl.Result{Requeue: true}, nil
 		}
 
-		newPod, err := r.newPod(runner)
+		// The pod is created with the updated token in the same reconcilation
+		if err := r.updateRegistrationToken(ctx, ghc, &runner); err != nil {
+			if retryAt, ok := github.RetryAt(err); ok {
+				return ctrl.Result{RequeueAfter: requeueAfter(retryAt)}, nil
+			}
+
+			return ctrl.Result{}, err
+		}
+
+		newPod, err := r.newPod(ghc, runner)
 		if err != nil {
 			log.Error(err, "Could not create pod")
 			return ctrl.Result{}, err
@@ -178,7 +214,7 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		log.Info("Created runner pod", "repository", runner.Spec.Repository)
 	} else {
 		if !pod.ObjectMeta.DeletionTimestamp.IsZero() {
-			deletionTimeout := 1 * time.Minute
+			deletionTimeout := runner.Spec.GetPodDeletionTimeout()
 			currentTime := time.Now()
 			deletionDidTimeout := currentTime.Sub(pod.DeletionTimestamp.Add(deletionTimeout)) > 0
 
@@ -217,6 +253,9 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		// Happens e.g. when dind is in runner and run completes
 		restart := pod.Status.Phase == corev1.PodSucceeded
 
+		// failed is set when the pod is recreated because the runner failed to register itself to GitHub
+		failed := false
+
 		if pod.Status.Phase == corev1.PodRunning {
 			for _, status := range pod.Status.ContainerStatuses {
 				if status.Name != containerName {
@@ -229,13 +268,36 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			}
 		}
 
-		if updated, err := r.updateRegistrationToken(ctx, runner); err != nil {
+		// An ephemeral runner accepts only one job, and its pod is never restarted.
+		// Once the runner container completes, we delete the whole Runner so that
+		// the owning RunnerReplicaSet replaces it with a fresh one.
+		// A pod that failed, like the one whose config.sh failed, is recreated after the backoff instead,
+		// so that repeated failures are counted and the runner eventually gives up like non-ephemeral ones.
+		if runner.Spec.IsEphemeral() && runnerPodFinished(pod) {
+			if !runnerPodFailed(pod) {
+				log.Info("Ephemeral runner pod has finished. Deleting the runner", "podPhase", pod.Status.Phase)
+
+				return r.deleteEphemeralRunner(ctx, runner)
+			}
+
+			reason, message := runnerPodFailureReason(pod)
+
+			log.Info("Ephemeral runner pod has failed", "podPhase", pod.Status.Phase, "reason", reason, "message", message)
+
+			restart = true
+			failed = true
+		}
+
+		ghc, err := r.GitHubClient.InitForRunner(ctx, &runner)
+		if err != nil {
+			log.Error(err, "Failed to get GitHub client for runner")
 			return ctrl.Result{}, err
-		} else if updated {
-			return ctrl.Result{Requeue: true}, nil
 		}
 
-		newPod, err := r.newPod(runner)
+		// The existing pod reads the registration token from the secret only on launch,
+		// so we don't need to update the token here.
+		// A new token is created right before a pod is recreated.
+		newPod, err := r.newPod(ghc, runner)
 		if err != nil {
 			log.Error(err, "Could not create pod")
 			return ctrl.Result{}, err
@@ -247,7 +309,7 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 		// if a restart was already decided before, there is no need for the checks
 		// saving API calls and scary{ log messages
 		if !restart {
-			registrationCheckInterval := time.Minute
+			registrationCheckInterval := runner.Spec.GetRegistrationCheckInterval()
 
 			// We want to call ListRunners GitHub Actions API only once per runner per minute.
 			// This if block, in conjunction with:
@@ -270,7 +332,7 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			notFound := false
 			offline := false
 
-			runnerBusy, err := r.GitHubClient.IsRunnerBusy(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name)
+			runnerBusy, err := ghc.IsRunnerBusy(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name)
 
 			currentTime := time.Now()
 
@@ -282,25 +344,36 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				} else if errors.As(err, &offlineException) {
 					offline = true
 				} else {
-					var e *gogithub.RateLimitError
-					if errors.As(err, &e) {
-						// We log the underlying error when we failed calling GitHub API to list or unregisters,
-						// or the runner is still busy.
-						log.Error(
-							err,
-							fmt.Sprintf(
-								"Failed to check if runner is busy due to Github API rate limit. Retrying in %s to avoid excessive GitHub API calls",
-								retryDelayOnGitHubAPIRateLimitError,
-							),
-						)
-
-						return ctrl.Result{RequeueAfter: retryDelayOnGitHubAPIRateLimitError}, err
+					if retryAt, ok := github.RetryAt(err); ok {
+						log.Error(err, "Failed to check if runner is busy due to GitHub API rate limit. Delaying retry until the rate limit resets", "retryAt", retryAt)
+
+						return ctrl.Result{RequeueAfter: requeueAfter(retryAt)}, nil
 					}
 
 					return ctrl.Result{}, err
 				}
 			}
 
+			// The runner has registered itself, and the token is no longer needed
+			if !notFound && !offline {
+				if err := r.deleteRegistrationTokenSecret(ctx, runner); err != nil {
+					return ctrl.Result{}, err
+				}
+
+				if runner.Status.FailureCount > 0 {
+					updated := runner.DeepCopy()
+					updated.Status.FailureCount = 0
+					updated.Status.LastFailureTime = nil
+
+					if err := r.Status().Patch(ctx, updated, client.MergeFrom(&runner)); err != nil {
+						log.Error(err, "Failed to update runner status")
+						return ctrl.Result{}, err
+					}
+
+					runner = *updated
+				}
+			}
+
 			// See the `newPod` function called above for more information
 			// about when this hash changes.
 			curHash := pod.Labels[LabelKeyPodTemplateHash]
@@ -310,7 +383,7 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 				restart = true
 			}
 
-			registrationTimeout := 10 * time.Minute
+			registrationTimeout := runner.Spec.GetRegistrationTimeout()
 			durationAfterRegistrationTimeout := currentTime.Sub(pod.CreationTimestamp.Add(registrationTimeout))
 			registrationDidTimeout := durationAfterRegistrationTimeout > 0
 
@@ -326,7 +399,10 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 						"configuredRegistrationTimeout", registrationTimeout,
 					)
 
+					metrics.IncRunnerRegistrationTimeouts(runner.Namespace, metrics.RegistrationTimeoutActionRecreatePod)
+
 					restart = true
+					failed = true
 				} else {
 					log.V(1).Info(
 						"Runner pod exists but we failed to check if runner is busy. Apparently it still needs more time.",
@@ -344,7 +420,10 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 						"configuredRegistrationTimeout", registrationTimeout,
 					)
 
+					metrics.IncRunnerRegistrationTimeouts(runner.Namespace, metrics.RegistrationTimeoutActionRecreatePod)
+
 					restart = true
+					failed = true
 				} else {
 					log.V(1).Info(
 						"Runner pod exists but the GitHub runner appears to be still offline. Waiting for runner to get online ...",
@@ -399,6 +478,18 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 			return ctrl.Result{}, nil
 		}
 
+		if failed {
+			gaveUp, err := r.recordRunnerFailure(ctx, &runner, pod)
+			if err != nil || gaveUp {
+				return ctrl.Result{}, err
+			}
+		}
+
+		// An ephemeral runner's pod is recreated only after failures, so we replace the whole runner instead
+		if runner.Spec.IsEphemeral() && !failed {
+			return r.deleteEphemeralRunner(ctx, runner)
+		}
+
 		// Delete current pod if recreation is needed
 		if err := r.Delete(ctx, &pod); err != nil {
 			log.Error(err, "Failed to delete pod resource")
@@ -412,8 +503,241 @@ func (r *RunnerReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
 	return ctrl.Result{}, nil
 }
 
-func (r *RunnerReconciler) unregisterRunner(ctx context.Context, enterprise, org, repo, name string) (bool, error) {
-	runners, err := r.GitHubClient.ListRunners(ctx, enterprise, org, repo)
+func (r *RunnerReconciler) deleteEphemeralRunner(ctx context.Context, runner v1alpha1.Runner) (ctrl.Result, error) {
+	log := r.Log.WithValues("runner", runner.Name)
+
+	if err := r.Delete(ctx, &runner); client.IgnoreNotFound(err) != nil {
+		log.Error(err, "Failed to delete ephemeral runner")
+		return ctrl.Result{}, err
+	}
+
+	r.Recorder.Event(&runner, corev1.EventTypeNormal, "RunnerDeleted", fmt.Sprintf("Deleted ephemeral runner '%s'", runner.Name))
+	log.Info("Deleted ephemeral runner", "repository", runner.Spec.Repository)
+
+	return ctrl.Result{}, nil
+}
+
+// recordRunnerFailure records the cause of the runner pod failing to register itself to GitHub to the runner status.
+// It returns true when the runner has failed too many times and its pod shouldn't be recreated anymore.
+// The pod is left as is in that case, so that the cause can be investigated.
+func (r *RunnerReconciler) recordRunnerFailure(ctx context.Context, runner *v1alpha1.Runner, pod corev1.Pod) (bool, error) {
+	log := r.Log.WithValues("runner", runner.Name)
+
+	reason, message := runnerPodFailureReason(pod)
+
+	updated := runner.DeepCopy()
+	updated.Status.FailureCount++
+	updated.Status.LastFailureTime = &metav1.Time{Time: time.Now()}
+	updated.Status.Reason = reason
+	updated.Status.Message = message
+
+	max := runner.Spec.GetMaxRegistrationFailures()
+	gaveUp := updated.Status.FailureCount >= max
+
+	if gaveUp {
+		updated.Status.Phase = string(corev1.PodFailed)
+	}
+
+	if err := r.Status().Patch(ctx, updated, client.MergeFrom(runner)); err != nil {
+		log.Error(err, "Failed to update runner status")
+		return false, err
+	}
+
+	*runner = *updated
+
+	if gaveUp {
+		r.Recorder.Event(runner, corev1.EventTypeWarning, "RunnerFailed", fmt.Sprintf("Gave up after %d failures to register the runner. Delete the runner to retry: %s: %s", max, reason, message))
+		log.Info("Runner failed to register itself to GitHub too many times. Giving up", "failureCount", updated.Status.FailureCount, "reason", reason, "message", message)
+
+		return true, nil
+	}
+
+	r.Recorder.Event(runner, corev1.EventTypeWarning, "RegistrationFailed", fmt.Sprintf("Recreating pod after failure %d/%d: %s: %s", updated.Status.FailureCount, max, reason, message))
+
+	return false, nil
+}
+
+// runnerConfigFailedExitCode is the exit code of the runner container whose config.sh failed. See runner/entrypoint.sh
+const runnerConfigFailedExitCode = 78
+
+// runnerPodFailureReason returns the likely cause of the runner pod failing to register itself to GitHub,
+// and the message describing it.
+func runnerPodFailureReason(pod corev1.Pod) (string, string) {
+	if pod.Status.Phase == corev1.PodUnknown || pod.Status.Reason == "NodeLost" {
+		return "NodeLost", fmt.Sprintf("Lost the node %q running the pod", pod.Spec.NodeName)
+	}
+
+	if pod.Status.Reason == "Evicted" {
+		return "Evicted", pod.Status.Message
+	}
+
+	for _, cond := range pod.Status.Conditions {
+		if cond.Type == corev1.PodScheduled && cond.Status == corev1.ConditionFalse {
+			return "Unschedulable", cond.Message
+		}
+	}
+
+	statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...), pod.Status.ContainerStatuses...)
+
+	for _, status := range statuses {
+		if waiting := status.State.Waiting; waiting != nil {
+			switch waiting.Reason {
+			case "ErrImagePull", "ImagePullBackOff", "InvalidImageName", "CreateContainerConfigError", "CreateContainerError":
+				return waiting.Reason, fmt.Sprintf("Container %q: %s", status.Name, waiting.Message)
+			}
+		}
+
+		for _, terminated := range []*corev1.ContainerStateTerminated{status.State.Terminated, status.LastTerminationState.Terminated} {
+			if terminated == nil {
+				continue
+			}
+
+			if terminated.Reason == "OOMKilled" {
+				return "OOMKilled", fmt.Sprintf("Container %q was killed due to out of memory", status.Name)
+			}
+
+			if status.Name == containerName && terminated.ExitCode == runnerConfigFailedExitCode {
+				return "RunnerConfigFailed", "config.sh failed to register the runner to GitHub. See the logs of the runner container for details"
+			}
+		}
+	}
+
+	for _, status := range statuses {
+		if waiting := status.State.Waiting; waiting != nil && waiting.Reason == "CrashLoopBackOff" {
+			return waiting.Reason, fmt.Sprintf("Container %q: %s", status.Name, waiting.Message)
+		}
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if terminated := status.State.Terminated; status.Name == containerName && terminated != nil && terminated.ExitCode != 0 {
+			return "RunnerExited", fmt.Sprintf("Container %q exited with code %d: %s", status.Name, terminated.ExitCode, terminated.Reason)
+		}
+	}
+
+	return "RegistrationTimeout", "Runner didn't register itself to GitHub in time"
+}
+
+// runnerFailureBackoff returns how long to wait before recreating the pod of the runner that has failed count times.
+func runnerFailureBackoff(count int32) time.Duration {
+	const (
+		initialDelay = 30 * time.Second
+		maxDelay     = 10 * time.Minute
+	)
+
+	delay := initialDelay
+
+	for i := int32(1); i < count; i++ {
+		delay *= 2
+
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+
+	return delay
+}
+
+// runnerPodFinished returns true when the runner container in the pod has terminated
+// and the kubelet is not going to restart it anymore.
+func runnerPodFinished(pod corev1.Pod) bool {
+	if pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed {
+		return true
+	}
+
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == containerName && status.State.Terminated != nil {
+			return true
+		}
+	}
+
+	return false
+}
+
+// runnerPodFailed returns true when the finished runner pod has failed rather than completed.
+// The runner container exits with 0 after running a job, so a pod terminated without the runner container,
+// like an evicted one, is considered failed as well.
+func runnerPodFailed(pod corev1.Pod) bool {
+	for _, status := range pod.Status.ContainerStatuses {
+		if status.Name == containerName && status.State.Terminated != nil {
+			return status.State.Terminated.ExitCode != 0
+		}
+	}
+
+	return pod.Status.Phase == corev1.PodFailed
+}
+
+// drainRunner waits for the busy runner being deleted to complete its running job,
+// or deletes its pod to cancel the job once the runner has been draining for MaxDrainDuration.
+func (r *RunnerReconciler) drainRunner(ctx context.Context, runner v1alpha1.Runner) (ctrl.Result, error) {
+	log := r.Log.WithValues("runner", runner.Name)
+
+	drainingFor := time.Since(runner.DeletionTimestamp.Time)
+
+	if runner.Status.Phase != v1alpha1.RunnerPhaseDraining {
+		updated := runner.DeepCopy()
+		updated.Status.Phase = v1alpha1.RunnerPhaseDraining
+		updated.Status.Reason = ""
+		updated.Status.Message = "Waiting for the running job to complete"
+
+		if err := r.Status().Patch(ctx, updated, client.MergeFrom(&runner)); err != nil {
+			log.Error(err, "Failed to update runner status")
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.Event(&runner, corev1.EventTypeNormal, "RunnerDraining", "Waiting for the running job to complete before removing the runner")
+	}
+
+	delay := drainCheckDelay(drainingFor)
+
+	if max := runner.Spec.MaxDrainDuration; max != nil {
+		if remaining := max.Duration - drainingFor; remaining <= 0 {
+			var pod corev1.Pod
+			if err := r.Get(ctx, types.NamespacedName{Namespace: runner.Namespace, Name: runner.Name}, &pod); err != nil {
+				if !kerrors.IsNotFound(err) {
+					return ctrl.Result{}, err
+				}
+			} else if pod.DeletionTimestamp.IsZero() {
+				if err := r.Delete(ctx, &pod); client.IgnoreNotFound(err) != nil {
+					log.Error(err, "Failed to delete pod resource")
+					return ctrl.Result{}, err
+				}
+
+				r.Recorder.Event(&runner, corev1.EventTypeWarning, "RunnerDrainTimeout", fmt.Sprintf("Deleted pod '%s' to cancel the running job that didn't complete within %s", pod.Name, max.Duration))
+				log.Info("Deleted runner pod to cancel the running job", "maxDrainDuration", max.Duration)
+			}
+
+			// The runner is removed from GitHub once it's no longer busy with the cancelled job
+		} else if remaining < delay {
+			delay = remaining
+		}
+	}
+
+	log.V(1).Info("Runner is busy. Waiting for the running job to complete", "drainingFor", drainingFor, "retryAfter", delay)
+
+	return ctrl.Result{RequeueAfter: delay}, nil
+}
+
+// drainCheckDelay returns the delay until the next check of the runner draining for drainingFor.
+// The delay grows with drainingFor, so that a long-running job doesn't result in many API calls.
+func drainCheckDelay(drainingFor time.Duration) time.Duration {
+	const (
+		minDelay = 10 * time.Second
+		maxDelay = 2 * time.Minute
+	)
+
+	if drainingFor < minDelay {
+		return minDelay
+	}
+
+	if drainingFor > maxDelay {
+		return maxDelay
+	}
+
+	return drainingFor
+}
+
+func (r *RunnerReconciler) unregisterRunner(ctx context.Context, ghc *github.Client, enterprise, org, repo, name string) (bool, error) {
+	runners, err := ghc.ListRunners(ctx, enterprise, org, repo)
 	if err != nil {
 		return false, err
 	}
@@ -422,7 +746,7 @@ func (r *RunnerReconciler) unregisterRunner(ctx context.Context, enterprise, org
 	for _, runner := range runners {
 		if runner.GetName() == name {
 			if runner.GetBusy() {
-				return false, fmt.Errorf("runner is busy")
+				return false, errRunnerBusy
 			}
 			id = runner.GetID()
 			break
@@ -433,25 +757,67 @@ func (r *RunnerReconciler) unregisterRunner(ctx context.Context, enterprise, org
 		return false, nil
 	}
 
-	if err := r.GitHubClient.RemoveRunner(ctx, enterprise, org, repo, id); err != nil {
+	if err := ghc.RemoveRunner(ctx, enterprise, org, repo, id); err != nil {
 		return false, err
 	}
 
 	return true, nil
 }
 
-func (r *RunnerReconciler) updateRegistrationToken(ctx context.Context, runner v1alpha1.Runner) (bool, error) {
-	if runner.IsRegisterable() {
-		return false, nil
+// updateRegistrationToken stores the registration token shared across the runners of the same scope to the secret of the runner,
+// when the secret doesn't have a valid one. runner is updated in place, so that the caller can go on creating the pod.
+func (r *RunnerReconciler) updateRegistrationToken(ctx context.Context, ghc *github.Client, runner *v1alpha1.Runner) error {
+	log := r.Log.WithValues("runner", runner.Name)
+
+	var secret corev1.Secret
+
+	secretExists := true
+
+	if err := r.Get(ctx, types.NamespacedName{Namespace: runner.Namespace, Name: runner.RegistrationTokenSecretName()}, &secret); err != nil {
+		if !kerrors.IsNotFound(err) {
+			log.Error(err, "Failed to get registration token secret")
+			return err
+		}
+
+		secretExists = false
 	}
 
-	log := r.Log.WithValues("runner", runner.Name)
+	if secretExists && runner.IsRegisterable(&secret) {
+		return nil
+	}
 
-	rt, err := r.GitHubClient.GetRegistrationToken(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name)
+	rt, err := ghc.GetRegistrationToken(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name)
 	if err != nil {
-		r.Recorder.Event(&runner, corev1.EventTypeWarning, "FailedUpdateRegistrationToken", "Updating registration token failed")
+		r.Recorder.Event(runner, corev1.EventTypeWarning, "FailedUpdateRegistrationToken", "Updating registration token failed")
 		log.Error(err, "Failed to get new registration token")
-		return false, err
+		metrics.IncRunnerRegistrationTokenFailures(runner.Namespace)
+		return err
+	}
+
+	updatedSecret := secret.DeepCopy()
+	updatedSecret.Name = runner.RegistrationTokenSecretName()
+	updatedSecret.Namespace = runner.Namespace
+	if updatedSecret.Annotations == nil {
+		updatedSecret.Annotations = map[string]string{}
+	}
+	updatedSecret.Annotations[v1alpha1.AnnotationKeyRegistrationTokenExpiresAt] = rt.GetExpiresAt().Format(time.RFC3339)
+	updatedSecret.Data = map[string][]byte{
+		v1alpha1.RegistrationTokenSecretKey: []byte(rt.GetToken()),
+	}
+
+	if secretExists {
+		err = r.Update(ctx, updatedSecret)
+	} else {
+		// The secret is garbage-collected along with the runner
+		if err := ctrl.SetControllerReference(runner, updatedSecret, r.Scheme); err != nil {
+			return err
+		}
+
+		err = r.Create(ctx, updatedSecret)
+	}
+	if err != nil {
+		log.Error(err, "Failed to write registration token secret")
+		return err
 	}
 
 	updated := runner.DeepCopy()
@@ -459,26 +825,47 @@ func (r *RunnerReconciler) updateRegistrationToken(ctx context.Context, runner v
 		Organization: runner.Spec.Organization,
 		Repository:   runner.Spec.Repository,
 		Labels:       runner.Spec.Labels,
-		Token:        rt.GetToken(),
 		ExpiresAt:    metav1.NewTime(rt.GetExpiresAt().Time),
 	}
 
 	if err := r.Status().Update(ctx, updated); err != nil {
 		log.Error(err, "Failed to update runner status")
-		return false, err
+		return err
 	}
 
-	r.Recorder.Event(&runner, corev1.EventTypeNormal, "RegistrationTokenUpdated", "Successfully update registration token")
+	r.Recorder.Event(runner, corev1.EventTypeNormal, "RegistrationTokenUpdated", "Successfully update registration token")
 	log.Info("Updated registration token", "repository", runner.Spec.Repository)
 
-	return true, nil
+	*runner = *updated
+
+	return nil
 }
 
-func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
+// deleteRegistrationTokenSecret deletes the secret of the runner once the runner has registered itself,
+// so that the token is no longer readable.
+func (r *RunnerReconciler) deleteRegistrationTokenSecret(ctx context.Context, runner v1alpha1.Runner) error {
+	var secret corev1.Secret
+
+	if err := r.Get(ctx, types.NamespacedName{Namespace: runner.Namespace, Name: runner.RegistrationTokenSecretName()}, &secret); err != nil {
+		return client.IgnoreNotFound(err)
+	}
+
+	if err := r.Delete(ctx, &secret); client.IgnoreNotFound(err) != nil {
+		r.Log.Error(err, "Failed to delete registration token secret", "runner", runner.Name)
+		return err
+	}
+
+	r.Log.V(1).Info("Deleted registration token secret of the registered runner", "runner", runner.Name)
+
+	return nil
+}
+
+func (r *RunnerReconciler) newPod(ghc *github.Client, runner v1alpha1.Runner) (corev1.Pod, error) {
 	var (
 		privileged      bool = true
 		dockerdInRunner bool = runner.Spec.DockerdWithinRunnerContainer != nil && *runner.Spec.DockerdWithinRunnerContainer
 		dockerEnabled   bool = runner.Spec.DockerEnabled == nil || *runner.Spec.DockerEnabled
+		optional        bool = true
 	)
 
 	runnerImage := runner.Spec.Image
@@ -522,8 +909,23 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 			Value: runner.Spec.Group,
 		},
 		{
-			Name:  "RUNNER_TOKEN",
-			Value: runner.Status.Registration.Token,
+			Name: "RUNNER_TOKEN",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{
+						Name: runner.RegistrationTokenSecretName(),
+					},
+					Key: v1alpha1.RegistrationTokenSecretKey,
+					// The secret is deleted once the runner is registered.
+					// Without this, the runner container fails to restart in-place after that.
+					// The restarted container reuses the configuration in the /runner emptyDir without the token. See runner/entrypoint.sh
+					Optional: &optional,
+				},
+			},
+		},
+		{
+			Name:  "RUNNER_EPHEMERAL",
+			Value: fmt.Sprintf("%v", runner.Spec.IsEphemeral()),
 		},
 		{
 			Name:  "DOCKERD_IN_RUNNER",
@@ -531,7 +933,7 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 		},
 		{
 			Name:  "GITHUB_URL",
-			Value: r.GitHubClient.GithubBaseURL,
+			Value: ghc.GithubBaseURL,
 		},
 		{
 			Name:  "RUNNER_WORKDIR",
@@ -556,7 +958,7 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 	// - GithubBaseURL setting of the controller (can be configured via GITHUB_ENTERPRISE_URL)
 	//
 	// (2) We don't recreate the runner pod when there are changes in:
-	// - runner.status.registration.token
+	// - the registration token stored in the secret of the runner
 	//   - This token expires and changes hourly, but you don't need to recreate the pod due to that.
 	//     It's the opposite.
 	//     An unexpired token is required only when the runner agent is registering itself on launch.
@@ -570,9 +972,16 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 		filterLabels(runner.Labels, LabelKeyRunnerTemplateHash),
 		runner.Annotations,
 		runner.Spec,
-		r.GitHubClient.GithubBaseURL,
+		ghc.GithubBaseURL,
 	)
 
+	// An ephemeral runner pod must not be restarted in-place, because the restarted container would
+	// reuse the work directory and the docker cache stored in the pod's emptyDir volumes.
+	restartPolicy := corev1.RestartPolicyOnFailure
+	if runner.Spec.IsEphemeral() {
+		restartPolicy = corev1.RestartPolicyNever
+	}
+
 	pod := corev1.Pod{
 		ObjectMeta: metav1.ObjectMeta{
 			Name:        runner.Name,
@@ -581,7 +990,7 @@ func (r *RunnerReconciler) newPod(runner v1alpha1.Runner) (corev1.Pod, error) {
 			Annotations: runner.Annotations,
 		},
 		Spec: corev1.PodSpec{
-			RestartPolicy: "OnFailure",
+			RestartPolicy: restartPolicy,
 			Containers: []corev1.Container{
 				{
 					Name:            containerName,
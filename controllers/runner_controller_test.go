@@ -0,0 +1,730 @@
+package controllers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	kfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
+	"github.com/summerwind/actions-runner-controller/github/fake"
+)
+
+func TestNewPod_Ephemeral(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	r := &RunnerReconciler{
+		Scheme: scheme,
+	}
+
+	ghc := &github.Client{GithubBaseURL: "https://github.com/"}
+
+	ephemeral := true
+
+	testcases := []struct {
+		ephemeral         *bool
+		wantRestartPolicy corev1.RestartPolicy
+		wantEnv           string
+	}{
+		{
+			ephemeral:         nil,
+			wantRestartPolicy: corev1.RestartPolicyOnFailure,
+			wantEnv:           "false",
+		},
+		{
+			ephemeral:         &ephemeral,
+			wantRestartPolicy: corev1.RestartPolicyNever,
+			wantEnv:           "true",
+		},
+	}
+
+	for i, tc := range testcases {
+		runner := v1alpha1.Runner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.RunnerSpec{
+				Repository: "test/valid",
+				Ephemeral:  tc.ephemeral,
+			},
+		}
+
+		pod, err := r.newPod(ghc, runner)
+		if err != nil {
+			t.Fatalf("%d: %v", i, err)
+		}
+
+		if pod.Spec.RestartPolicy != tc.wantRestartPolicy {
+			t.Errorf("%d: unexpected restart policy: want %s, got %s", i, tc.wantRestartPolicy, pod.Spec.RestartPolicy)
+		}
+
+		var got string
+		for _, env := range pod.Spec.Containers[0].Env {
+			if env.Name == "RUNNER_EPHEMERAL" {
+				got = env.Value
+			}
+		}
+
+		if got != tc.wantEnv {
+			t.Errorf("%d: unexpected RUNNER_EPHEMERAL: want %q, got %q", i, tc.wantEnv, got)
+		}
+	}
+}
+
+func TestRunnerPodFinished(t *testing.T) {
+	testcases := []struct {
+		name string
+		pod  corev1.Pod
+		want bool
+	}{
+		{
+			name: "running",
+			pod: corev1.Pod{
+				Status: corev1.PodStatus{
+					Phase: corev1.PodRunning,
+					ContainerStatuses: []corev1.ContainerStatus{
+						{Name: containerName, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+					},
+				},
+			},
+			want: false,
+		},
+		{
+			name: "succeeded",
+			pod: corev1.Pod{
+				Status: corev1.PodStatus{Phase: corev1.PodSucceeded},
+			},
+			want: true,
+		},
+		{
+			name: "failed",
+			pod: corev1.Pod{
+				Status: corev1.PodStatus{Phase: corev1.PodFailed},
+			},
+			want: true,
+		},
+		{
+			name: "runner terminated while docker sidecar is running",
+			pod: corev1.Pod{
+				Status: corev1.PodStatus{
+					Phase: corev1.PodRunning,
+					ContainerStatuses: []corev1.ContainerStatus{
+						{Name: containerName, State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 0}}},
+						{Name: "docker", State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+					},
+				},
+			},
+			want: true,
+		},
+		{
+			name: "sidecar terminated",
+			pod: corev1.Pod{
+				Status: corev1.PodStatus{
+					Phase: corev1.PodRunning,
+					ContainerStatuses: []corev1.ContainerStatus{
+						{Name: containerName, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+						{Name: "docker", State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: 1}}},
+					},
+				},
+			},
+			want: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := runnerPodFinished(tc.pod); got != tc.want {
+				t.Errorf("runnerPodFinished() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestRegistrationTokenSecret(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	runner := &v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.RunnerSpec{
+			Repository: "test/valid",
+		},
+	}
+
+	client := kfake.NewFakeClientWithScheme(scheme, runner)
+
+	r := &RunnerReconciler{
+		Client:   client,
+		Log:      zap.New(),
+		Recorder: record.NewFakeRecorder(10),
+		Scheme:   scheme,
+	}
+
+	server := httptest.NewServer(&fake.Handler{
+		Status: http.StatusCreated,
+		Body:   fmt.Sprintf(`{"token": "%s", "expires_at": "%s"}`, fake.RegistrationToken, time.Now().Add(time.Hour).Format(time.RFC3339)),
+	})
+	defer server.Close()
+
+	ghc := newGithubClient(server)
+
+	ctx := context.Background()
+
+	if err := r.updateRegistrationToken(ctx, ghc, runner); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	key := types.NamespacedName{Namespace: runner.Namespace, Name: runner.RegistrationTokenSecretName()}
+
+	var secret corev1.Secret
+	if err := client.Get(ctx, key, &secret); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if got := string(secret.Data[v1alpha1.RegistrationTokenSecretKey]); got != fake.RegistrationToken {
+		t.Errorf("unexpected token: want %q, got %q", fake.RegistrationToken, got)
+	}
+
+	if len(secret.OwnerReferences) != 1 || secret.OwnerReferences[0].Name != runner.Name {
+		t.Errorf("expected the secret to be owned by the runner, got %v", secret.OwnerReferences)
+	}
+
+	if runner.Status.Registration.ExpiresAt.IsZero() {
+		t.Errorf("expected the expiration time of the token in the runner status")
+	}
+
+	if !runner.IsRegisterable(&secret) {
+		t.Errorf("expected the runner to be registerable with the secret")
+	}
+
+	// The valid token is reused without updating the secret
+	if err := r.updateRegistrationToken(ctx, ghc, runner); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var unchanged corev1.Secret
+	if err := client.Get(ctx, key, &unchanged); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if unchanged.ResourceVersion != secret.ResourceVersion {
+		t.Errorf("expected the secret not to be updated: want resource version %s, got %s", secret.ResourceVersion, unchanged.ResourceVersion)
+	}
+
+	pod, err := r.newPod(ghc, *runner)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	for _, env := range pod.Spec.Containers[0].Env {
+		if env.Name != "RUNNER_TOKEN" {
+			continue
+		}
+
+		if env.Value != "" {
+			t.Errorf("expected RUNNER_TOKEN not to contain the token itself, got %q", env.Value)
+		}
+
+		if ref := env.ValueFrom.SecretKeyRef; ref == nil || ref.Name != key.Name || ref.Key != v1alpha1.RegistrationTokenSecretKey {
+			t.Errorf("unexpected RUNNER_TOKEN source: %v", env.ValueFrom)
+		}
+	}
+
+	if err := r.deleteRegistrationTokenSecret(ctx, *runner); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if err := client.Get(ctx, key, &secret); !kerrors.IsNotFound(err) {
+		t.Errorf("expected the secret to be deleted, got %v", err)
+	}
+
+	if runner.IsRegisterable(nil) {
+		t.Errorf("expected the runner not to be registerable without the secret")
+	}
+
+	if err := r.deleteRegistrationTokenSecret(ctx, *runner); err != nil {
+		t.Errorf("unexpected error on deleting the missing secret: %v", err)
+	}
+}
+
+func TestDrainRunner(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	testcases := []struct {
+		name             string
+		drainingFor      time.Duration
+		maxDrainDuration *metav1.Duration
+		wantPodDeleted   bool
+		wantRequeueAfter time.Duration
+		wantEvents       []string
+	}{
+		{
+			name:             "no limit",
+			drainingFor:      5 * time.Hour,
+			wantRequeueAfter: 2 * time.Minute,
+			wantEvents:       []string{"RunnerDraining"},
+		},
+		{
+			name:             "within max drain duration",
+			drainingFor:      30 * time.Second,
+			maxDrainDuration: &metav1.Duration{Duration: time.Hour},
+			wantRequeueAfter: 30 * time.Second,
+			wantEvents:       []string{"RunnerDraining"},
+		},
+		{
+			name:             "max drain duration passed",
+			drainingFor:      2 * time.Hour,
+			maxDrainDuration: &metav1.Duration{Duration: time.Hour},
+			wantPodDeleted:   true,
+			wantRequeueAfter: 2 * time.Minute,
+			wantEvents:       []string{"RunnerDraining", "RunnerDrainTimeout"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &v1alpha1.Runner{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "example",
+					Namespace:         "default",
+					DeletionTimestamp: &metav1.Time{Time: time.Now().Add(-tc.drainingFor)},
+					Finalizers:        []string{finalizerName},
+				},
+				Spec: v1alpha1.RunnerSpec{
+					Repository:       "test/valid",
+					MaxDrainDuration: tc.maxDrainDuration,
+				},
+			}
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "example",
+					Namespace: "default",
+				},
+			}
+
+			client := kfake.NewFakeClientWithScheme(scheme, runner, pod)
+			recorder := record.NewFakeRecorder(10)
+
+			r := &RunnerReconciler{
+				Client:   client,
+				Log:      zap.New(),
+				Recorder: recorder,
+				Scheme:   scheme,
+			}
+
+			ctx := context.Background()
+
+			result, err := r.drainRunner(ctx, *runner)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if d := result.RequeueAfter - tc.wantRequeueAfter; d < -time.Second || d > time.Second {
+				t.Errorf("unexpected requeue delay: want %s, got %s", tc.wantRequeueAfter, result.RequeueAfter)
+			}
+
+			var updated v1alpha1.Runner
+			if err := client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, &updated); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			if updated.Status.Phase != v1alpha1.RunnerPhaseDraining {
+				t.Errorf("unexpected phase: want %s, got %s", v1alpha1.RunnerPhaseDraining, updated.Status.Phase)
+			}
+
+			err = client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, &corev1.Pod{})
+			if podDeleted := kerrors.IsNotFound(err); podDeleted != tc.wantPodDeleted {
+				t.Errorf("unexpected pod deletion: want %v, got %v", tc.wantPodDeleted, podDeleted)
+			}
+
+			close(recorder.Events)
+
+			var events []string
+			for e := range recorder.Events {
+				events = append(events, strings.Fields(e)[1])
+			}
+
+			if !reflect.DeepEqual(events, tc.wantEvents) {
+				t.Errorf("unexpected events: want %v, got %v", tc.wantEvents, events)
+			}
+		})
+	}
+}
+
+func TestReconcile_DeletedRunnerWithMissingCredentials(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	runner := &v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:              "example",
+			Namespace:         "default",
+			DeletionTimestamp: &metav1.Time{Time: time.Now()},
+			Finalizers:        []string{finalizerName},
+		},
+		Spec: v1alpha1.RunnerSpec{
+			Repository: "test/valid",
+			GitHubAPICredentialsFrom: &v1alpha1.GitHubAPICredentialsFrom{
+				SecretRef: v1alpha1.SecretReference{Name: "deleted-github"},
+			},
+		},
+		Status: v1alpha1.RunnerStatus{
+			Registration: v1alpha1.RunnerStatusRegistration{
+				Repository: "test/valid",
+				ExpiresAt:  metav1.Time{Time: time.Now().Add(time.Hour)},
+			},
+		},
+	}
+
+	client := kfake.NewFakeClientWithScheme(scheme, runner)
+	recorder := record.NewFakeRecorder(10)
+
+	r := &RunnerReconciler{
+		Client:       client,
+		Log:          zap.New(),
+		Recorder:     recorder,
+		Scheme:       scheme,
+		GitHubClient: NewMultiGitHubClient(client, &github.Client{}, github.Config{}),
+	}
+
+	ctx := context.Background()
+
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "example"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var updated v1alpha1.Runner
+	if err := client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, &updated); err != nil && !kerrors.IsNotFound(err) {
+		t.Fatalf("%v", err)
+	}
+
+	if len(updated.Finalizers) != 0 {
+		t.Errorf("unexpected finalizers: want none, got %v", updated.Finalizers)
+	}
+
+	close(recorder.Events)
+
+	var events []string
+	for e := range recorder.Events {
+		events = append(events, strings.Fields(e)[1])
+	}
+
+	if want := []string{"UnregistrationSkipped"}; !reflect.DeepEqual(events, want) {
+		t.Errorf("unexpected events: want %v, got %v", want, events)
+	}
+}
+
+func TestReconcile_FinishedEphemeralRunner(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	ephemeral := true
+
+	testcases := []struct {
+		name              string
+		exitCode          int32
+		wantRunnerDeleted bool
+		wantFailureCount  int32
+		wantReason        string
+		wantEvents        []string
+	}{
+		{
+			name:              "completed",
+			exitCode:          0,
+			wantRunnerDeleted: true,
+			wantEvents:        []string{"RunnerDeleted"},
+		},
+		{
+			name:             "config failed",
+			exitCode:         runnerConfigFailedExitCode,
+			wantFailureCount: 1,
+			wantReason:       "RunnerConfigFailed",
+			wantEvents:       []string{"RegistrationFailed", "PodDeleted"},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			runner := &v1alpha1.Runner{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:       "example",
+					Namespace:  "default",
+					Finalizers: []string{finalizerName},
+				},
+				Spec: v1alpha1.RunnerSpec{
+					Repository: "test/valid",
+					Ephemeral:  &ephemeral,
+				},
+			}
+
+			pod := &corev1.Pod{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "example",
+					Namespace: "default",
+				},
+				Status: corev1.PodStatus{
+					Phase: corev1.PodFailed,
+					ContainerStatuses: []corev1.ContainerStatus{
+						{Name: containerName, State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{ExitCode: tc.exitCode}}},
+					},
+				},
+			}
+
+			if tc.exitCode == 0 {
+				pod.Status.Phase = corev1.PodSucceeded
+			}
+
+			client := kfake.NewFakeClientWithScheme(scheme, runner, pod)
+			recorder := record.NewFakeRecorder(10)
+
+			r := &RunnerReconciler{
+				Client:       client,
+				Log:          zap.New(),
+				Recorder:     recorder,
+				Scheme:       scheme,
+				GitHubClient: NewMultiGitHubClient(client, &github.Client{}, github.Config{}),
+			}
+
+			ctx := context.Background()
+			key := types.NamespacedName{Namespace: "default", Name: "example"}
+
+			if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var updated v1alpha1.Runner
+			err := client.Get(ctx, key, &updated)
+			if runnerDeleted := kerrors.IsNotFound(err); runnerDeleted != tc.wantRunnerDeleted {
+				t.Fatalf("unexpected runner deletion: want %v, got %v", tc.wantRunnerDeleted, runnerDeleted)
+			}
+
+			if !tc.wantRunnerDeleted {
+				if updated.Status.FailureCount != tc.wantFailureCount {
+					t.Errorf("unexpected failure count: want %d, got %d", tc.wantFailureCount, updated.Status.FailureCount)
+				}
+
+				if updated.Status.Reason != tc.wantReason {
+					t.Errorf("unexpected reason: want %s, got %s", tc.wantReason, updated.Status.Reason)
+				}
+
+				if err := client.Get(ctx, key, &corev1.Pod{}); !kerrors.IsNotFound(err) {
+					t.Errorf("expected the failed pod to be deleted for recreation, got %v", err)
+				}
+			}
+
+			close(recorder.Events)
+
+			var events []string
+			for e := range recorder.Events {
+				events = append(events, strings.Fields(e)[1])
+			}
+
+			if !reflect.DeepEqual(events, tc.wantEvents) {
+				t.Errorf("unexpected events: want %v, got %v", tc.wantEvents, events)
+			}
+		})
+	}
+}
+
+func TestRunnerPodFailureReason(t *testing.T) {
+	testcases := []struct {
+		name   string
+		status corev1.PodStatus
+		want   string
+	}{
+		{
+			name:   "node lost",
+			status: corev1.PodStatus{Phase: corev1.PodUnknown},
+			want:   "NodeLost",
+		},
+		{
+			name:   "evicted",
+			status: corev1.PodStatus{Phase: corev1.PodFailed, Reason: "Evicted"},
+			want:   "Evicted",
+		},
+		{
+			name: "unschedulable",
+			status: corev1.PodStatus{
+				Phase:      corev1.PodPending,
+				Conditions: []corev1.PodCondition{{Type: corev1.PodScheduled, Status: corev1.ConditionFalse, Reason: "Unschedulable"}},
+			},
+			want: "Unschedulable",
+		},
+		{
+			name: "image pull",
+			status: corev1.PodStatus{
+				Phase: corev1.PodPending,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: containerName, State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ImagePullBackOff"}}},
+				},
+			},
+			want: "ImagePullBackOff",
+		},
+		{
+			name: "oom killed sidecar",
+			status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: containerName, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+					{Name: "docker", State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}, LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137}}},
+				},
+			},
+			want: "OOMKilled",
+		},
+		{
+			name: "config.sh failure",
+			status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: containerName, State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}, LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: runnerConfigFailedExitCode}}},
+				},
+			},
+			want: "RunnerConfigFailed",
+		},
+		{
+			name: "crash loop",
+			status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: containerName, State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "CrashLoopBackOff"}}, LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}}},
+				},
+			},
+			want: "CrashLoopBackOff",
+		},
+		{
+			name: "ephemeral runner exited",
+			status: corev1.PodStatus{
+				Phase: corev1.PodFailed,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: containerName, State: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: "Error", ExitCode: 1}}},
+				},
+			},
+			want: "RunnerExited",
+		},
+		{
+			name: "running but not registered",
+			status: corev1.PodStatus{
+				Phase: corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{
+					{Name: containerName, State: corev1.ContainerState{Running: &corev1.ContainerStateRunning{}}},
+				},
+			},
+			want: "RegistrationTimeout",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, _ := runnerPodFailureReason(corev1.Pod{Status: tc.status})
+			if got != tc.want {
+				t.Errorf("unexpected reason: want %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestRunnerFailureBackoff(t *testing.T) {
+	want := []time.Duration{30 * time.Second, time.Minute, 2 * time.Minute, 4 * time.Minute, 8 * time.Minute, 10 * time.Minute, 10 * time.Minute}
+
+	for i, w := range want {
+		if got := runnerFailureBackoff(int32(i + 1)); got != w {
+			t.Errorf("unexpected backoff after %d failures: want %s, got %s", i+1, w, got)
+		}
+	}
+}
+
+func TestRecordRunnerFailure(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	maxFailures := int32(2)
+
+	runner := &v1alpha1.Runner{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+		},
+		Spec: v1alpha1.RunnerSpec{
+			Repository:              "test/valid",
+			MaxRegistrationFailures: &maxFailures,
+		},
+	}
+
+	pod := corev1.Pod{
+		Status: corev1.PodStatus{
+			Phase: corev1.PodPending,
+			ContainerStatuses: []corev1.ContainerStatus{
+				{Name: containerName, State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: "ErrImagePull"}}},
+			},
+		},
+	}
+
+	client := kfake.NewFakeClientWithScheme(scheme, runner)
+
+	r := &RunnerReconciler{
+		Client:   client,
+		Log:      zap.New(),
+		Recorder: record.NewFakeRecorder(10),
+		Scheme:   scheme,
+	}
+
+	ctx := context.Background()
+
+	for i, wantGaveUp := range []bool{false, true} {
+		gaveUp, err := r.recordRunnerFailure(ctx, runner, pod)
+		if err != nil {
+			t.Fatalf("%d: unexpected error: %v", i, err)
+		}
+
+		if gaveUp != wantGaveUp {
+			t.Errorf("%d: unexpected result: want %v, got %v", i, wantGaveUp, gaveUp)
+		}
+	}
+
+	var updated v1alpha1.Runner
+	if err := client.Get(ctx, types.NamespacedName{Namespace: "default", Name: "example"}, &updated); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if updated.Status.FailureCount != 2 {
+		t.Errorf("unexpected failure count: want 2, got %d", updated.Status.FailureCount)
+	}
+
+	if updated.Status.Reason != "ErrImagePull" {
+		t.Errorf("unexpected reason: want ErrImagePull, got %s", updated.Status.Reason)
+	}
+
+	if updated.Status.Phase != string(corev1.PodFailed) {
+		t.Errorf("unexpected phase: want %s, got %s", corev1.PodFailed, updated.Status.Phase)
+	}
+}
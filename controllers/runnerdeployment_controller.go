@@ -22,6 +22,7 @@ import (
 	"hash/fnv"
 	"reflect"
 	"sort"
+	"strconv"
 	"time"
 
 	"k8s.io/apimachinery/pkg/types"
@@ -35,9 +36,12 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/client"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 
 	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/controllers/metrics"
 )
 
 const (
@@ -70,13 +74,32 @@ func (r *RunnerDeploymentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 
 	var rd v1alpha1.RunnerDeployment
 	if err := r.Get(ctx, req.NamespacedName, &rd); err != nil {
+		if kerrors.IsNotFound(err) {
+			metrics.DeleteRunnerDeployment(req.Namespace, req.Name)
+		}
+
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
 
 	if !rd.ObjectMeta.DeletionTimestamp.IsZero() {
+		metrics.DeleteRunnerDeployment(rd.Namespace, rd.Name)
+
 		return ctrl.Result{}, nil
 	}
 
+	var myRunnerList v1alpha1.RunnerList
+	if err := r.List(ctx, &myRunnerList, client.InNamespace(req.Namespace), client.MatchingLabels{LabelKeyRunnerDeploymentName: req.Name}); err != nil {
+		return ctrl.Result{}, err
+	}
+
+	runnerPhases := map[string]int{}
+
+	for _, runner := range myRunnerList.Items {
+		runnerPhases[runner.Status.Phase]++
+	}
+
+	metrics.SetRunnerDeploymentRunners(req.Namespace, req.Name, runnerPhases)
+
 	var myRunnerReplicaSetList v1alpha1.RunnerReplicaSetList
 	if err := r.List(ctx, &myRunnerReplicaSetList, client.InNamespace(req.Namespace), client.MatchingFields{runnerSetOwnerKey: req.Name}); err != nil {
 		return ctrl.Result{}, err
@@ -84,7 +107,13 @@ func (r *RunnerDeploymentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 
 	myRunnerReplicaSets := myRunnerReplicaSetList.Items
 
+	// Sets created before we started recording revisions are older than any set with a revision
 	sort.Slice(myRunnerReplicaSets, func(i, j int) bool {
+		ri, rj := getRevision(&myRunnerReplicaSets[i]), getRevision(&myRunnerReplicaSets[j])
+		if ri != rj {
+			return ri > rj
+		}
+
 		return myRunnerReplicaSets[i].GetCreationTimestamp().After(myRunnerReplicaSets[j].GetCreationTimestamp().Time)
 	})
 
@@ -109,7 +138,40 @@ func (r *RunnerDeploymentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 		return ctrl.Result{}, err
 	}
 
+	desiredTemplateHash, ok := getTemplateHash(desiredRS)
+	if !ok {
+		log.Info("Failed to get template hash of desired runnerreplicaset resource. It must be in an invalid state. Please manually delete the runnerreplicaset so that it is recreated")
+
+		return ctrl.Result{}, nil
+	}
+
+	// The status is reported even while paused, so that one can see the runners during incidents
+	if status := computeRunnerDeploymentStatus(rd, myRunnerReplicaSets, desiredTemplateHash, time.Now()); !equality.Semantic.DeepEqual(rd.Status, status) {
+		updated := rd.DeepCopy()
+		updated.Status = status
+
+		if err := r.Status().Patch(ctx, updated, client.MergeFrom(&rd)); err != nil {
+			log.Error(err, "Failed to update runnerdeployment status")
+
+			return ctrl.Result{}, err
+		}
+
+		rd = *updated
+	}
+
+	if rd.Spec.Paused {
+		log.V(1).Info("Skipped reconciling runnerreplicasets as the runnerdeployment is paused")
+
+		return ctrl.Result{}, nil
+	}
+
+	if rd.Spec.RollbackTo != nil {
+		return r.rollback(ctx, log, rd, myRunnerReplicaSets)
+	}
+
 	if newestSet == nil {
+		setRevision(desiredRS, 1)
+
 		if err := r.Client.Create(ctx, desiredRS); err != nil {
 			log.Error(err, "Failed to create runnerreplicaset resource")
 
@@ -126,14 +188,48 @@ func (r *RunnerDeploymentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 		return ctrl.Result{}, nil
 	}
 
-	desiredTemplateHash, ok := getTemplateHash(desiredRS)
-	if !ok {
-		log.Info("Failed to get template hash of desired runnerreplicaset resource. It must be in an invalid state. Please manually delete the runnerreplicaset so that it is recreated")
-
-		return ctrl.Result{}, nil
-	}
+	const defaultReplicas = 1
 
 	if newestTemplateHash != desiredTemplateHash {
+		nextRevision := getRevision(newestSet) + 1
+
+		// The template is reverted to the one of an old set, so the old set takes over the runners of the other sets
+		// instead of creating a new set with the same template.
+		for i := range oldSets {
+			rs := oldSets[i]
+
+			if hash, _ := getTemplateHash(&rs); hash != desiredTemplateHash {
+				continue
+			}
+
+			setRevision(&rs, nextRevision)
+
+			if err := r.Client.Update(ctx, &rs); err != nil {
+				log.Error(err, "Failed to update runnerreplicaset resource")
+
+				return ctrl.Result{}, err
+			}
+
+			log.Info("Reused runnerreplicaset for the template", "runnerreplicaset", rs.Name, "revision", nextRevision)
+
+			return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
+		}
+
+		desired := getIntOrDefault(desiredRS.Spec.Replicas, defaultReplicas)
+
+		maxSurge, maxUnavailable, err := getMaxSurgeAndUnavailable(rd, desired)
+		if err != nil {
+			log.Error(err, "Invalid rollout strategy")
+
+			return ctrl.Result{}, nil
+		}
+
+		// The new set starts with as many runners as the surge allows, and takes over the rest while the old sets scale down
+		replicas, _ := rolloutReplicas(desired, maxSurge, maxUnavailable, v1alpha1.RunnerReplicaSet{Spec: v1alpha1.RunnerReplicaSetSpec{Replicas: new(int)}}, myRunnerReplicaSets)
+		desiredRS.Spec.Replicas = &replicas
+
+		setRevision(desiredRS, nextRevision)
+
 		if err := r.Client.Create(ctx, desiredRS); err != nil {
 			log.Error(err, "Failed to create runnerreplicaset resource")
 
@@ -167,13 +263,43 @@ func (r *RunnerDeploymentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 		return ctrl.Result{RequeueAfter: 5 * time.Second}, nil
 	}
 
-	const defaultReplicas = 1
+	// The scale down policy doesn't change the runners, so it's updated in place,
+	// including the old sets that scale down during a rollout.
+	for i := range myRunnerReplicaSets {
+		rs := &myRunnerReplicaSets[i]
+
+		if rs.Spec.ScaleDownPolicy == desiredRS.Spec.ScaleDownPolicy {
+			continue
+		}
+
+		rs.Spec.ScaleDownPolicy = desiredRS.Spec.ScaleDownPolicy
+
+		if err := r.Client.Update(ctx, rs); err != nil {
+			log.Error(err, "Failed to update runnerreplicaset resource")
+
+			return ctrl.Result{}, err
+		}
+	}
 
 	currentDesiredReplicas := getIntOrDefault(newestSet.Spec.Replicas, defaultReplicas)
 	newDesiredReplicas := getIntOrDefault(desiredRS.Spec.Replicas, defaultReplicas)
 
+	// Old sets that still have runners are being replaced by the newest set.
+	// The other old sets are kept at 0 replicas as the revision history.
+	isActive := func(rs v1alpha1.RunnerReplicaSet) bool {
+		return getIntOrDefault(rs.Spec.Replicas, defaultReplicas) > 0 || rs.Status.AvailableReplicas > 0
+	}
+
+	var rollingOut bool
+
+	for _, rs := range oldSets {
+		if isActive(rs) {
+			rollingOut = true
+		}
+	}
+
 	// Please add more conditions that we can in-place update the newest runnerreplicaset without disruption
-	if currentDesiredReplicas != newDesiredReplicas {
+	if !rollingOut && currentDesiredReplicas != newDesiredReplicas {
 		newestSet.Spec.Replicas = &newDesiredReplicas
 
 		if err := r.Client.Update(ctx, newestSet); err != nil {
@@ -185,36 +311,90 @@ func (r *RunnerDeploymentReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 		return ctrl.Result{}, err
 	}
 
-	// Do we old runner replica sets that should eventually deleted?
-	if len(oldSets) > 0 {
-		readyReplicas := newestSet.Status.ReadyReplicas
+	if rollingOut {
+		maxSurge, maxUnavailable, err := getMaxSurgeAndUnavailable(rd, newDesiredReplicas)
+		if err != nil {
+			log.Error(err, "Invalid rollout strategy")
+
+			return ctrl.Result{}, nil
+		}
 
-		if readyReplicas < currentDesiredReplicas {
+		newReplicas, oldReplicas := rolloutReplicas(newDesiredReplicas, maxSurge, maxUnavailable, *newestSet, oldSets)
+
+		var remaining int
+
+		for i := range oldSets {
+			rs := &oldSets[i]
+
+			if getIntOrDefault(rs.Spec.Replicas, defaultReplicas) != oldReplicas[i] {
+				rs.Spec.Replicas = &oldReplicas[i]
+
+				if err := r.Client.Update(ctx, rs); err != nil {
+					log.Error(err, "Failed to update runnerreplicaset resource")
+
+					return ctrl.Result{}, err
+				}
+
+				log.Info("Scaled down old runnerreplicaset", "runnerreplicaset", rs.Name, "replicas", oldReplicas[i])
+			}
+
+			// Busy runners of the old set are removed by the runnerreplicaset controller once they complete their jobs
+			if isActive(*rs) {
+				remaining++
+			}
+		}
+
+		if currentDesiredReplicas != newReplicas {
+			newestSet.Spec.Replicas = &newReplicas
+
+			if err := r.Client.Update(ctx, newestSet); err != nil {
+				log.Error(err, "Failed to update runnerreplicaset resource")
+
+				return ctrl.Result{}, err
+			}
+
+			log.Info("Scaled up newest runnerreplicaset", "runnerreplicaset", newestSet.Name, "replicas", newReplicas)
+		}
+
+		if remaining > 0 {
 			log.WithValues("runnerreplicaset", types.NamespacedName{
 				Namespace: newestSet.Namespace,
 				Name:      newestSet.Name,
 			}).
-				Info("Waiting until the newest runner replica set to be 100% available",
-					"ready", readyReplicas,
-					"desired", currentDesiredReplicas,
+				Info("Waiting until the old runner replica sets to be scaled down",
+					"ready", newestSet.Status.ReadyReplicas,
+					"desired", newDesiredReplicas,
+					"oldSets", remaining,
 				)
 
 			return ctrl.Result{RequeueAfter: 10 * time.Second}, nil
 		}
+	}
 
-		for i := range oldSets {
-			rs := oldSets[i]
+	var history int
 
-			if err := r.Client.Delete(ctx, &rs); err != nil {
-				log.Error(err, "Failed to delete runnerreplicaset resource")
+	for i := range oldSets {
+		rs := oldSets[i]
 
-				return ctrl.Result{}, err
-			}
+		if isActive(rs) {
+			continue
+		}
 
-			r.Recorder.Event(&rd, corev1.EventTypeNormal, "RunnerReplicaSetDeleted", fmt.Sprintf("Deleted runnerreplicaset '%s'", rs.Name))
+		history++
 
-			log.Info("Deleted runnerreplicaset", "runnerdeployment", rd.ObjectMeta.Name, "runnerreplicaset", rs.Name)
+		if history <= rd.Spec.GetRevisionHistoryLimit() {
+			continue
 		}
+
+		if err := r.Client.Delete(ctx, &rs); err != nil {
+			log.Error(err, "Failed to delete runnerreplicaset resource")
+
+			return ctrl.Result{}, err
+		}
+
+		r.Recorder.Event(&rd, corev1.EventTypeNormal, "RunnerReplicaSetDeleted", fmt.Sprintf("Deleted runnerreplicaset '%s'", rs.Name))
+
+		log.Info("Deleted runnerreplicaset", "runnerdeployment", rd.ObjectMeta.Name, "runnerreplicaset", rs.Name)
 	}
 
 	if rd.Spec.Replicas == nil && desiredRS.Spec.Replicas != nil {
@@ -239,6 +419,106 @@ func getIntOrDefault(p *int, d int) int {
 	return *p
 }
 
+// rollback replaces the template of the runnerdeployment with the one of the revision to roll back to.
+// The runnerdeployment is then reconciled again to roll out the template.
+func (r *RunnerDeploymentReconciler) rollback(ctx context.Context, log logr.Logger, rd v1alpha1.RunnerDeployment, sets []v1alpha1.RunnerReplicaSet) (ctrl.Result, error) {
+	revision := rd.Spec.RollbackTo.Revision
+
+	var target *v1alpha1.RunnerReplicaSet
+
+	for i := range sets {
+		// The sets are sorted from the newest to the oldest, so the second one is the previous revision
+		if revision == 0 && i == 1 || revision != 0 && getRevision(&sets[i]) == revision {
+			target = &sets[i]
+			break
+		}
+	}
+
+	updated := rd.DeepCopy()
+	updated.Spec.RollbackTo = nil
+
+	if target != nil {
+		updated.Spec.Template = getRunnerDeploymentTemplate(target, r.CommonRunnerLabels)
+	}
+
+	if err := r.Client.Update(ctx, updated); err != nil {
+		log.Error(err, "Failed to update runnerdeployment resource")
+
+		return ctrl.Result{}, err
+	}
+
+	if target == nil {
+		r.Recorder.Event(&rd, corev1.EventTypeWarning, "RollbackRevisionNotFound", fmt.Sprintf("Unable to find revision %d to roll back to", revision))
+
+		log.Info("Unable to find revision to roll back to", "revision", revision)
+
+		return ctrl.Result{}, nil
+	}
+
+	r.Recorder.Event(&rd, corev1.EventTypeNormal, "RollbackDone", fmt.Sprintf("Rolled back to revision %d of runnerreplicaset '%s'", getRevision(target), target.Name))
+
+	log.Info("Rolled back runnerdeployment template", "revision", getRevision(target), "runnerreplicaset", target.Name)
+
+	return ctrl.Result{}, nil
+}
+
+// getRunnerDeploymentTemplate returns the runnerdeployment template the runnerreplicaset is created from,
+// by removing what newRunnerReplicaSet adds to it.
+func getRunnerDeploymentTemplate(rs *v1alpha1.RunnerReplicaSet, commonRunnerLabels []string) v1alpha1.RunnerTemplate {
+	template := *rs.Spec.Template.DeepCopy()
+
+	delete(template.ObjectMeta.Labels, LabelKeyRunnerTemplateHash)
+	delete(template.ObjectMeta.Labels, LabelKeyRunnerDeploymentName)
+
+	if len(template.ObjectMeta.Labels) == 0 {
+		template.ObjectMeta.Labels = nil
+	}
+
+	labels := template.Spec.Labels
+
+	if n := len(labels) - len(commonRunnerLabels); n >= 0 {
+		hasCommonLabels := true
+
+		for i, l := range commonRunnerLabels {
+			if labels[n+i] != l {
+				hasCommonLabels = false
+				break
+			}
+		}
+
+		if hasCommonLabels {
+			labels = labels[:n]
+		}
+	}
+
+	if len(labels) == 0 {
+		labels = nil
+	}
+
+	template.Spec.Labels = labels
+
+	return template
+}
+
+// getRevision returns the revision of the runnerdeployment template the runnerreplicaset is created for,
+// or 0 for the runnerreplicasets created before we started recording revisions.
+func getRevision(rs *v1alpha1.RunnerReplicaSet) int64 {
+	revision, err := strconv.ParseInt(rs.Annotations[v1alpha1.AnnotationKeyRevision], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return revision
+}
+
+func setRevision(rs *v1alpha1.RunnerReplicaSet, revision int64) {
+	if rs.Annotations == nil {
+		rs.Annotations = map[string]string{}
+	}
+
+	rs.Annotations[v1alpha1.AnnotationKeyRevision] = strconv.FormatInt(revision, 10)
+}
+
 func getTemplateHash(rs *v1alpha1.RunnerReplicaSet) (string, bool) {
 	hash, ok := rs.Labels[LabelKeyRunnerTemplateHash]
 
@@ -366,9 +646,10 @@ func newRunnerReplicaSet(rd *v1alpha1.RunnerDeployment, commonRunnerLabels []str
 			Labels:       newRSTemplate.ObjectMeta.Labels,
 		},
 		Spec: v1alpha1.RunnerReplicaSetSpec{
-			Replicas: rd.Spec.Replicas,
-			Selector: newRSSelector,
-			Template: newRSTemplate,
+			Replicas:        rd.Spec.Replicas,
+			Selector:        newRSSelector,
+			Template:        newRSTemplate,
+			ScaleDownPolicy: rd.Spec.ScaleDownPolicy,
 		},
 	}
 
@@ -3,6 +3,7 @@ package controllers
 import (
 	"context"
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
@@ -12,6 +13,7 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
 
@@ -19,6 +21,8 @@ import (
 	. "github.com/onsi/gomega"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	kfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 
 	actionsv1alpha1 "github.com/summerwind/actions-runner-controller/api/v1alpha1"
 )
@@ -120,6 +124,300 @@ func TestNewRunnerReplicaSet(t *testing.T) {
 	}
 }
 
+func TestRollback(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := actionsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	newRD := func(label string) *actionsv1alpha1.RunnerDeployment {
+		return &actionsv1alpha1.RunnerDeployment{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "example",
+				Namespace: "default",
+			},
+			Spec: actionsv1alpha1.RunnerDeploymentSpec{
+				Template: actionsv1alpha1.RunnerTemplate{
+					ObjectMeta: metav1.ObjectMeta{
+						Labels: map[string]string{
+							"foo": "bar",
+						},
+					},
+					Spec: actionsv1alpha1.RunnerSpec{
+						Repository: "test/valid",
+						Labels:     []string{label},
+					},
+				},
+			},
+		}
+	}
+
+	r := &RunnerDeploymentReconciler{
+		CommonRunnerLabels: []string{"dev"},
+		Scheme:             scheme,
+		Log:                zap.New(),
+	}
+
+	newRS := func(label string, revision int64) actionsv1alpha1.RunnerReplicaSet {
+		rs, err := r.newRunnerReplicaSet(*newRD(label))
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		rs.Name = fmt.Sprintf("example-%d", revision)
+		setRevision(rs, revision)
+
+		return *rs
+	}
+
+	sets := []actionsv1alpha1.RunnerReplicaSet{newRS("project3", 3), newRS("project2", 2), newRS("project1", 1)}
+
+	testcases := []struct {
+		revision  int64
+		wantLabel string
+		wantEvent string
+	}{
+		{revision: 0, wantLabel: "project2", wantEvent: "RollbackDone"},
+		{revision: 1, wantLabel: "project1", wantEvent: "RollbackDone"},
+		{revision: 4, wantLabel: "project3", wantEvent: "RollbackRevisionNotFound"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(fmt.Sprintf("revision %d", tc.revision), func(t *testing.T) {
+			rd := newRD("project3")
+			rd.Spec.RollbackTo = &actionsv1alpha1.RollbackConfig{Revision: tc.revision}
+
+			recorder := record.NewFakeRecorder(10)
+
+			r.Client = kfake.NewFakeClientWithScheme(scheme, rd)
+			r.Recorder = recorder
+
+			if _, err := r.rollback(context.Background(), r.Log, *rd, sets); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			var updated actionsv1alpha1.RunnerDeployment
+			if err := r.Client.Get(context.Background(), types.NamespacedName{Namespace: rd.Namespace, Name: rd.Name}, &updated); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			if updated.Spec.RollbackTo != nil {
+				t.Errorf("expected rollbackTo to be cleared, got %v", updated.Spec.RollbackTo)
+			}
+
+			if d := cmp.Diff(newRD(tc.wantLabel).Spec.Template, updated.Spec.Template); d != "" {
+				t.Errorf("unexpected template: (-want +got)\n%s", d)
+			}
+
+			rs, err := r.newRunnerReplicaSet(updated)
+			if err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			want := newRS(tc.wantLabel, 0)
+			if got, _ := getTemplateHash(rs); got != want.Labels[LabelKeyRunnerTemplateHash] {
+				t.Errorf("unexpected template hash after rollback: want %s, got %s", want.Labels[LabelKeyRunnerTemplateHash], got)
+			}
+
+			select {
+			case e := <-recorder.Events:
+				if !strings.Contains(e, tc.wantEvent) {
+					t.Errorf("unexpected event: want %s, got %q", tc.wantEvent, e)
+				}
+			default:
+				t.Errorf("expected %s event, got none", tc.wantEvent)
+			}
+		})
+	}
+}
+
+func TestReconcile_Paused(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := actionsv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	replicas := 3
+
+	rd := &actionsv1alpha1.RunnerDeployment{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example",
+			Namespace: "default",
+		},
+		Spec: actionsv1alpha1.RunnerDeploymentSpec{
+			Replicas: &replicas,
+			Paused:   true,
+			Template: actionsv1alpha1.RunnerTemplate{
+				Spec: actionsv1alpha1.RunnerSpec{
+					Repository: "test/valid",
+				},
+			},
+		},
+	}
+
+	rsReplicas := 2
+
+	rs := &actionsv1alpha1.RunnerReplicaSet{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "example-abcde",
+			Namespace: "default",
+		},
+		Spec: actionsv1alpha1.RunnerReplicaSetSpec{
+			Replicas: &rsReplicas,
+		},
+		Status: actionsv1alpha1.RunnerReplicaSetStatus{
+			AvailableReplicas: 2,
+			ReadyReplicas:     1,
+		},
+	}
+
+	client := kfake.NewFakeClientWithScheme(scheme, rd, rs)
+
+	r := &RunnerDeploymentReconciler{
+		Client:   client,
+		Scheme:   scheme,
+		Log:      zap.New(),
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	key := types.NamespacedName{Namespace: "default", Name: "example"}
+
+	if _, err := r.Reconcile(ctrl.Request{NamespacedName: key}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var updated actionsv1alpha1.RunnerDeployment
+	if err := client.Get(context.Background(), key, &updated); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if updated.Status.AvailableReplicas != 2 || updated.Status.ReadyReplicas != 1 {
+		t.Errorf("unexpected status: want 2 available and 1 ready replicas, got %d and %d", updated.Status.AvailableReplicas, updated.Status.ReadyReplicas)
+	}
+
+	var unchanged actionsv1alpha1.RunnerReplicaSet
+	if err := client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: rs.Name}, &unchanged); err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	if *unchanged.Spec.Replicas != rsReplicas {
+		t.Errorf("expected the replicas of the paused runnerreplicaset to be kept, got %d", *unchanged.Spec.Replicas)
+	}
+}
+
+func TestComputeRunnerDeploymentStatus(t *testing.T) {
+	now := time.Now()
+
+	newRS := func(name, hash string, available, ready, registered, busy int, progressing corev1.ConditionStatus) actionsv1alpha1.RunnerReplicaSet {
+		rs := actionsv1alpha1.RunnerReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:   name,
+				Labels: map[string]string{LabelKeyRunnerTemplateHash: hash},
+			},
+			Status: actionsv1alpha1.RunnerReplicaSetStatus{
+				AvailableReplicas:  available,
+				ReadyReplicas:      ready,
+				RegisteredReplicas: registered,
+				BusyReplicas:       busy,
+			},
+		}
+
+		failed := 0
+		if progressing == corev1.ConditionFalse {
+			failed = 1
+		}
+
+		setRunnerReplicaSetConditions(&rs.Status, available, failed, now)
+
+		return rs
+	}
+
+	testcases := []struct {
+		description     string
+		paused          bool
+		sets            []actionsv1alpha1.RunnerReplicaSet
+		wantUpdated     int
+		wantRegistered  int
+		wantBusy        int
+		wantAvailable   string
+		wantProgressing string
+	}{
+		{
+			description:     "rolling out",
+			sets:            []actionsv1alpha1.RunnerReplicaSet{newRS("new", "desired", 2, 1, 1, 0, corev1.ConditionTrue), newRS("old", "old", 3, 3, 3, 2, corev1.ConditionTrue)},
+			wantUpdated:     2,
+			wantRegistered:  4,
+			wantBusy:        2,
+			wantAvailable:   "True/MinimumReplicasAvailable",
+			wantProgressing: "True/RollingUpdate",
+		},
+		{
+			description:     "completed",
+			sets:            []actionsv1alpha1.RunnerReplicaSet{newRS("new", "desired", 4, 4, 4, 1, corev1.ConditionTrue)},
+			wantUpdated:     4,
+			wantRegistered:  4,
+			wantBusy:        1,
+			wantAvailable:   "True/MinimumReplicasAvailable",
+			wantProgressing: "True/NewRunnerReplicaSetAvailable",
+		},
+		{
+			description:     "runners failed to register",
+			sets:            []actionsv1alpha1.RunnerReplicaSet{newRS("new", "desired", 4, 4, 2, 0, corev1.ConditionFalse)},
+			wantUpdated:     4,
+			wantRegistered:  2,
+			wantAvailable:   "False/MinimumReplicasUnavailable",
+			wantProgressing: "False/RunnersFailed",
+		},
+		{
+			description:     "paused",
+			paused:          true,
+			sets:            []actionsv1alpha1.RunnerReplicaSet{newRS("old", "old", 4, 4, 4, 0, corev1.ConditionTrue)},
+			wantRegistered:  4,
+			wantAvailable:   "True/MinimumReplicasAvailable",
+			wantProgressing: "Unknown/DeploymentPaused",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			rd := actionsv1alpha1.RunnerDeployment{
+				ObjectMeta: metav1.ObjectMeta{
+					Generation: 2,
+				},
+				Spec: actionsv1alpha1.RunnerDeploymentSpec{
+					Replicas: intPtr(4),
+					Paused:   tc.paused,
+				},
+			}
+
+			status := computeRunnerDeploymentStatus(rd, tc.sets, "desired", now)
+
+			if status.ObservedGeneration != 2 {
+				t.Errorf("unexpected observed generation: want 2, got %d", status.ObservedGeneration)
+			}
+
+			if status.UpdatedReplicas != tc.wantUpdated || status.RegisteredReplicas != tc.wantRegistered || status.BusyReplicas != tc.wantBusy {
+				t.Errorf("unexpected replicas: want updated=%d registered=%d busy=%d, got updated=%d registered=%d busy=%d",
+					tc.wantUpdated, tc.wantRegistered, tc.wantBusy, status.UpdatedReplicas, status.RegisteredReplicas, status.BusyReplicas)
+			}
+
+			conditions := map[actionsv1alpha1.RunnerDeploymentConditionType]string{}
+			for _, c := range status.Conditions {
+				conditions[c.Type] = fmt.Sprintf("%s/%s", c.Status, c.Reason)
+			}
+
+			wantConditions := map[actionsv1alpha1.RunnerDeploymentConditionType]string{
+				actionsv1alpha1.RunnerDeploymentAvailable:   tc.wantAvailable,
+				actionsv1alpha1.RunnerDeploymentProgressing: tc.wantProgressing,
+			}
+
+			if d := cmp.Diff(wantConditions, conditions); d != "" {
+				t.Errorf("unexpected conditions: %s", d)
+			}
+		})
+	}
+}
+
 // SetupDeploymentTest will set up a testing environment.
 // This includes:
 // * creating a Namespace to be used during the test
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+)
+
+var (
+	defaultMaxSurge       = intstr.FromString("25%")
+	defaultMaxUnavailable = intstr.FromString("25%")
+)
+
+// getMaxSurgeAndUnavailable resolves the rollout strategy of the runnerdeployment against the desired replicas.
+func getMaxSurgeAndUnavailable(rd v1alpha1.RunnerDeployment, desired int) (int, int, error) {
+	maxSurge := defaultMaxSurge
+	if rd.Spec.Strategy.MaxSurge != nil {
+		maxSurge = *rd.Spec.Strategy.MaxSurge
+	}
+
+	maxUnavailable := defaultMaxUnavailable
+	if rd.Spec.Strategy.MaxUnavailable != nil {
+		maxUnavailable = *rd.Spec.Strategy.MaxUnavailable
+	}
+
+	surge, err := intstr.GetValueFromIntOrPercent(&maxSurge, desired, true)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid maxSurge: %w", err)
+	}
+
+	unavailable, err := intstr.GetValueFromIntOrPercent(&maxUnavailable, desired, false)
+	if err != nil {
+		return 0, 0, fmt.Errorf("invalid maxUnavailable: %w", err)
+	}
+
+	if surge < 0 {
+		surge = 0
+	}
+
+	if unavailable < 0 {
+		unavailable = 0
+	}
+
+	// Otherwise we can neither create a new runner nor remove an old one
+	if surge == 0 && unavailable == 0 {
+		unavailable = 1
+	}
+
+	return surge, unavailable, nil
+}
+
+// rolloutReplicas computes the replicas of the newest and the old runnerreplicasets for the next step of a rollout.
+//
+// The old sets are expected to be sorted from the newest to the oldest, and are scaled down starting with the oldest one
+// as long as there are at least desired-maxUnavailable ready runners.
+// The newest set is scaled up as long as there are at most desired+maxSurge runners, including the ones of the old sets
+// that are yet to be removed, because the runnerreplicaset controller removes only idle runners.
+func rolloutReplicas(desired, maxSurge, maxUnavailable int, newest v1alpha1.RunnerReplicaSet, oldSets []v1alpha1.RunnerReplicaSet) (int, []int) {
+	const defaultReplicas = 1
+
+	newReplicas := getIntOrDefault(newest.Spec.Replicas, defaultReplicas)
+
+	// Ready runners over the replicas of their set are going to be removed, and don't count as the capacity.
+	// This also prevents us from scaling down an old set twice before its status catches up.
+	ready := minInt(newest.Status.ReadyReplicas, newReplicas)
+	for _, rs := range oldSets {
+		ready += minInt(rs.Status.ReadyReplicas, getIntOrDefault(rs.Spec.Replicas, defaultReplicas))
+	}
+
+	budget := ready - (desired - maxUnavailable)
+	if budget < 0 {
+		budget = 0
+	}
+
+	oldReplicas := make([]int, len(oldSets))
+
+	for i := len(oldSets) - 1; i >= 0; i-- {
+		rs := oldSets[i]
+
+		replicas := getIntOrDefault(rs.Spec.Replicas, defaultReplicas)
+
+		// Runners that aren't ready yet can be removed without reducing the capacity
+		unhealthy := minInt(rs.Status.AvailableReplicas, replicas) - minInt(rs.Status.ReadyReplicas, replicas)
+		if unhealthy < 0 {
+			unhealthy = 0
+		}
+
+		scaleDown := unhealthy + budget
+		if scaleDown > replicas {
+			scaleDown = replicas
+		}
+
+		if scaleDown > unhealthy {
+			budget -= scaleDown - unhealthy
+		}
+
+		oldReplicas[i] = replicas - scaleDown
+	}
+
+	if newReplicas >= desired {
+		return desired, oldReplicas
+	}
+
+	total := newReplicas
+	for i, rs := range oldSets {
+		if rs.Status.AvailableReplicas > oldReplicas[i] {
+			total += rs.Status.AvailableReplicas
+		} else {
+			total += oldReplicas[i]
+		}
+	}
+
+	if room := desired + maxSurge - total; room > 0 {
+		newReplicas += room
+	}
+
+	if newReplicas > desired {
+		newReplicas = desired
+	}
+
+	return newReplicas, oldReplicas
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+
+	return b
+}
@@ -0,0 +1,137 @@
+package controllers
+
+import (
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+)
+
+func TestGetMaxSurgeAndUnavailable(t *testing.T) {
+	intOrStr := func(v intstr.IntOrString) *intstr.IntOrString {
+		return &v
+	}
+
+	testcases := []struct {
+		desired         int
+		maxSurge        *intstr.IntOrString
+		maxUnavailable  *intstr.IntOrString
+		wantSurge       int
+		wantUnavailable int
+		wantErr         bool
+	}{
+		{desired: 4, wantSurge: 1, wantUnavailable: 1},
+		{desired: 1, wantSurge: 1, wantUnavailable: 0},
+		{desired: 10, maxSurge: intOrStr(intstr.FromInt(0)), maxUnavailable: intOrStr(intstr.FromString("50%")), wantSurge: 0, wantUnavailable: 5},
+		{desired: 3, maxSurge: intOrStr(intstr.FromInt(0)), maxUnavailable: intOrStr(intstr.FromInt(0)), wantSurge: 0, wantUnavailable: 1},
+		{desired: 3, maxSurge: intOrStr(intstr.FromString("foo")), wantErr: true},
+	}
+
+	for i, tc := range testcases {
+		rd := v1alpha1.RunnerDeployment{
+			Spec: v1alpha1.RunnerDeploymentSpec{
+				Strategy: v1alpha1.RunnerDeploymentStrategy{
+					MaxSurge:       tc.maxSurge,
+					MaxUnavailable: tc.maxUnavailable,
+				},
+			},
+		}
+
+		surge, unavailable, err := getMaxSurgeAndUnavailable(rd, tc.desired)
+		if tc.wantErr {
+			if err == nil {
+				t.Errorf("#%d: expected error, got none", i)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("#%d: unexpected error: %v", i, err)
+			continue
+		}
+
+		if surge != tc.wantSurge || unavailable != tc.wantUnavailable {
+			t.Errorf("#%d: unexpected result: want (%d, %d), got (%d, %d)", i, tc.wantSurge, tc.wantUnavailable, surge, unavailable)
+		}
+	}
+}
+
+func TestRolloutReplicas(t *testing.T) {
+	rs := func(replicas, available, ready int) v1alpha1.RunnerReplicaSet {
+		return v1alpha1.RunnerReplicaSet{
+			Spec: v1alpha1.RunnerReplicaSetSpec{
+				Replicas: &replicas,
+			},
+			Status: v1alpha1.RunnerReplicaSetStatus{
+				AvailableReplicas: available,
+				ReadyReplicas:     ready,
+			},
+		}
+	}
+
+	testcases := []struct {
+		description string
+		newest      v1alpha1.RunnerReplicaSet
+		oldSets     []v1alpha1.RunnerReplicaSet
+		wantNew     int
+		wantOld     []int
+	}{
+		{
+			description: "new set is just created",
+			newest:      rs(0, 0, 0),
+			oldSets:     []v1alpha1.RunnerReplicaSet{rs(4, 4, 4)},
+			wantNew:     1,
+			wantOld:     []int{3},
+		},
+		{
+			description: "new runner is ready",
+			newest:      rs(1, 1, 1),
+			oldSets:     []v1alpha1.RunnerReplicaSet{rs(3, 3, 3)},
+			wantNew:     2,
+			wantOld:     []int{2},
+		},
+		{
+			description: "new runner isn't ready yet and a busy old runner is yet to be removed",
+			newest:      rs(1, 1, 0),
+			oldSets:     []v1alpha1.RunnerReplicaSet{rs(3, 4, 4)},
+			wantNew:     1,
+			wantOld:     []int{3},
+		},
+		{
+			description: "unhealthy old runners are removed without the budget",
+			newest:      rs(0, 0, 0),
+			oldSets:     []v1alpha1.RunnerReplicaSet{rs(4, 4, 2)},
+			wantNew:     1,
+			wantOld:     []int{2},
+		},
+		{
+			description: "oldest set is scaled down first",
+			newest:      rs(0, 0, 0),
+			oldSets:     []v1alpha1.RunnerReplicaSet{rs(2, 2, 2), rs(2, 2, 2)},
+			wantNew:     1,
+			wantOld:     []int{2, 1},
+		},
+		{
+			description: "rollout waits for a busy old runner",
+			newest:      rs(4, 4, 4),
+			oldSets:     []v1alpha1.RunnerReplicaSet{rs(0, 1, 1)},
+			wantNew:     4,
+			wantOld:     []int{0},
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.description, func(t *testing.T) {
+			gotNew, gotOld := rolloutReplicas(4, 1, 1, tc.newest, tc.oldSets)
+
+			if gotNew != tc.wantNew {
+				t.Errorf("unexpected replicas of the newest set: want %d, got %d", tc.wantNew, gotNew)
+			}
+
+			if d := cmp.Diff(tc.wantOld, gotOld); d != "" {
+				t.Errorf("unexpected replicas of the old sets: (-want +got)\n%s", d)
+			}
+		})
+	}
+}
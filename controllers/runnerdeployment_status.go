@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// setRunnerDeploymentCondition adds or updates the condition of the type.
+// The last transition time is updated only when the status of the condition changes.
+func setRunnerDeploymentCondition(conditions []v1alpha1.RunnerDeploymentCondition, conditionType v1alpha1.RunnerDeploymentConditionType, status corev1.ConditionStatus, reason, message string, now time.Time) []v1alpha1.RunnerDeploymentCondition {
+	for i := range conditions {
+		c := &conditions[i]
+
+		if c.Type != conditionType {
+			continue
+		}
+
+		if c.Status != status {
+			c.Status = status
+			c.LastTransitionTime = metav1.Time{Time: now}
+		}
+
+		c.Reason = reason
+		c.Message = message
+
+		return conditions
+	}
+
+	return append(conditions, v1alpha1.RunnerDeploymentCondition{
+		Type:               conditionType,
+		Status:             status,
+		LastTransitionTime: metav1.Time{Time: now},
+		Reason:             reason,
+		Message:            message,
+	})
+}
+
+func getRunnerDeploymentCondition(conditions []v1alpha1.RunnerDeploymentCondition, conditionType v1alpha1.RunnerDeploymentConditionType) *v1alpha1.RunnerDeploymentCondition {
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+
+	return nil
+}
+
+// setRunnerReplicaSetConditions updates the conditions of the runnerreplicaset from the numbers of runners in the status.
+// failed is the number of runners that have given up registering to GitHub.
+func setRunnerReplicaSetConditions(status *v1alpha1.RunnerReplicaSetStatus, desired, failed int, now time.Time) {
+	if status.RegisteredReplicas >= desired {
+		status.Conditions = setRunnerDeploymentCondition(status.Conditions, v1alpha1.RunnerDeploymentAvailable, corev1.ConditionTrue, "MinimumReplicasAvailable", fmt.Sprintf("%d of %d runners are registered to GitHub", status.RegisteredReplicas, desired), now)
+	} else {
+		status.Conditions = setRunnerDeploymentCondition(status.Conditions, v1alpha1.RunnerDeploymentAvailable, corev1.ConditionFalse, "MinimumReplicasUnavailable", fmt.Sprintf("%d of %d runners are registered to GitHub", status.RegisteredReplicas, desired), now)
+	}
+
+	if failed > 0 {
+		status.Conditions = setRunnerDeploymentCondition(status.Conditions, v1alpha1.RunnerDeploymentProgressing, corev1.ConditionFalse, "RunnersFailed", fmt.Sprintf("%d runners gave up registering to GitHub after repeated failures", failed), now)
+	} else if status.AvailableReplicas != desired {
+		status.Conditions = setRunnerDeploymentCondition(status.Conditions, v1alpha1.RunnerDeploymentProgressing, corev1.ConditionTrue, "Scaling", fmt.Sprintf("scaling from %d to %d runners", status.AvailableReplicas, desired), now)
+	} else {
+		status.Conditions = setRunnerDeploymentCondition(status.Conditions, v1alpha1.RunnerDeploymentProgressing, corev1.ConditionTrue, "RunnersCreated", fmt.Sprintf("%d runners are created", desired), now)
+	}
+}
+
+// computeRunnerDeploymentStatus aggregates the status of the runnerreplicasets of the runnerdeployment.
+// The runners of the runnerreplicaset with templateHash are the updated ones.
+func computeRunnerDeploymentStatus(rd v1alpha1.RunnerDeployment, sets []v1alpha1.RunnerReplicaSet, templateHash string, now time.Time) v1alpha1.RunnerDeploymentStatus {
+	status := *rd.Status.DeepCopy()
+
+	status.ObservedGeneration = rd.Generation
+	status.AvailableReplicas = 0
+	status.ReadyReplicas = 0
+	status.UpdatedReplicas = 0
+	status.RegisteredReplicas = 0
+	status.BusyReplicas = 0
+
+	var updatedSet *v1alpha1.RunnerReplicaSet
+
+	for i := range sets {
+		rs := &sets[i]
+
+		status.AvailableReplicas += rs.Status.AvailableReplicas
+		status.ReadyReplicas += rs.Status.ReadyReplicas
+		status.RegisteredReplicas += rs.Status.RegisteredReplicas
+		status.BusyReplicas += rs.Status.BusyReplicas
+
+		if hash, _ := getTemplateHash(rs); hash == templateHash {
+			status.UpdatedReplicas += rs.Status.AvailableReplicas
+			updatedSet = rs
+		}
+	}
+
+	desired := getIntOrDefault(rd.Spec.Replicas, 1)
+
+	// Old runners still run jobs during a rollout, so the deployment is available as long as the strategy allows
+	var maxUnavailable int
+	if _, unavailable, err := getMaxSurgeAndUnavailable(rd, desired); err == nil {
+		maxUnavailable = unavailable
+	}
+
+	if status.RegisteredReplicas >= desired-maxUnavailable {
+		status.Conditions = setRunnerDeploymentCondition(status.Conditions, v1alpha1.RunnerDeploymentAvailable, corev1.ConditionTrue, "MinimumReplicasAvailable", fmt.Sprintf("%d of %d runners are registered to GitHub", status.RegisteredReplicas, desired), now)
+	} else {
+		status.Conditions = setRunnerDeploymentCondition(status.Conditions, v1alpha1.RunnerDeploymentAvailable, corev1.ConditionFalse, "MinimumReplicasUnavailable", fmt.Sprintf("%d of %d runners are registered to GitHub", status.RegisteredReplicas, desired), now)
+	}
+
+	var failed *v1alpha1.RunnerDeploymentCondition
+	if updatedSet != nil {
+		if c := getRunnerDeploymentCondition(updatedSet.Status.Conditions, v1alpha1.RunnerDeploymentProgressing); c != nil && c.Status == corev1.ConditionFalse {
+			failed = c
+		}
+	}
+
+	if rd.Spec.Paused {
+		status.Conditions = setRunnerDeploymentCondition(status.Conditions, v1alpha1.RunnerDeploymentProgressing, corev1.ConditionUnknown, "DeploymentPaused", "the runnerdeployment is paused", now)
+	} else if failed != nil {
+		status.Conditions = setRunnerDeploymentCondition(status.Conditions, v1alpha1.RunnerDeploymentProgressing, corev1.ConditionFalse, failed.Reason, fmt.Sprintf("runnerreplicaset %q: %s", updatedSet.Name, failed.Message), now)
+	} else if old := status.AvailableReplicas - status.UpdatedReplicas; updatedSet == nil || old > 0 {
+		status.Conditions = setRunnerDeploymentCondition(status.Conditions, v1alpha1.RunnerDeploymentProgressing, corev1.ConditionTrue, "RollingUpdate", fmt.Sprintf("%d of %d runners are updated, %d old runners are yet to be removed", status.UpdatedReplicas, desired, old), now)
+	} else if status.UpdatedReplicas != desired {
+		status.Conditions = setRunnerDeploymentCondition(status.Conditions, v1alpha1.RunnerDeploymentProgressing, corev1.ConditionTrue, "Scaling", fmt.Sprintf("scaling from %d to %d runners", status.UpdatedReplicas, desired), now)
+	} else {
+		status.Conditions = setRunnerDeploymentCondition(status.Conditions, v1alpha1.RunnerDeploymentProgressing, corev1.ConditionTrue, "NewRunnerReplicaSetAvailable", fmt.Sprintf("runnerreplicaset %q has %d updated runners", updatedSet.Name, desired), now)
+	}
+
+	return status
+}
@@ -22,9 +22,8 @@ import (
 	"fmt"
 	"time"
 
-	gogithub "github.com/google/go-github/v33/github"
-
 	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
 	kerrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/tools/record"
@@ -33,8 +32,10 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 
 	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/controllers/metrics"
 	"github.com/summerwind/actions-runner-controller/github"
 )
 
@@ -44,7 +45,7 @@ type RunnerReplicaSetReconciler struct {
 	Log          logr.Logger
 	Recorder     record.EventRecorder
 	Scheme       *runtime.Scheme
-	GitHubClient *github.Client
+	GitHubClient *MultiGitHubClient
 	Name         string
 }
 
@@ -53,6 +54,7 @@ type RunnerReplicaSetReconciler struct {
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runnerreplicasets/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=actions.summerwind.dev,resources=runners/status,verbs=get;update;patch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
 // +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 func (r *RunnerReplicaSetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, error) {
@@ -90,6 +92,7 @@ func (r *RunnerReplicaSetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 	var (
 		available int
 		ready     int
+		failed    int
 	)
 
 	for _, r := range allRunners.Items {
@@ -98,11 +101,21 @@ func (r *RunnerReplicaSetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 		if metav1.IsControlledBy(&r, &rs) {
 			myRunners = append(myRunners, r)
 
+			// A runner that is being deleted, like an ephemeral runner that has finished its job,
+			// is replaced right away instead of waiting for its finalizer to complete.
+			if !r.ObjectMeta.DeletionTimestamp.IsZero() {
+				continue
+			}
+
 			available += 1
 
 			if r.Status.Phase == string(corev1.PodRunning) {
 				ready += 1
 			}
+
+			if r.Status.FailureCount >= r.Spec.GetMaxRegistrationFailures() {
+				failed += 1
+			}
 		}
 	}
 
@@ -121,8 +134,26 @@ func (r *RunnerReplicaSetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 
 		// get runners that are currently not busy
 		var notBusy []v1alpha1.Runner
-		for _, runner := range allRunners.Items {
-			busy, err := r.GitHubClient.IsRunnerBusy(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name)
+
+		// unregistered is the set of the names of the not busy runners that failed to register to GitHub or are offline
+		unregistered := map[string]bool{}
+
+		// registering is the set of the names of the runners that are yet to register to GitHub within the registration timeout
+		registering := map[string]bool{}
+
+		for _, runner := range myRunners {
+			if !runner.ObjectMeta.DeletionTimestamp.IsZero() {
+				continue
+			}
+
+			ghc, err := r.GitHubClient.InitForRunner(ctx, &runner)
+			if err != nil {
+				// We can't tell if the runner is busy, so we don't delete it
+				log.Error(err, "Failed to get GitHub client for runner. Skipping it on scale down", "runnerName", runner.Name)
+				continue
+			}
+
+			busy, err := ghc.IsRunnerBusy(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name)
 			if err != nil {
 				notRegistered := false
 				offline := false
@@ -135,27 +166,33 @@ func (r *RunnerReplicaSetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 				} else if errors.As(err, &offlineException) {
 					offline = true
 				} else {
-					var e *gogithub.RateLimitError
-					if errors.As(err, &e) {
-						// We log the underlying error when we failed calling GitHub API to list or unregisters,
-						// or the runner is still busy.
-						log.Error(
-							err,
-							fmt.Sprintf(
-								"Failed to check if runner is busy due to GitHub API rate limit. Retrying in %s to avoid excessive GitHub API calls",
-								retryDelayOnGitHubAPIRateLimitError,
-							),
-						)
-
-						return ctrl.Result{RequeueAfter: retryDelayOnGitHubAPIRateLimitError}, err
+					if retryAt, ok := github.RetryAt(err); ok {
+						log.Error(err, "Failed to check if runner is busy due to GitHub API rate limit. Delaying retry until the rate limit resets", "retryAt", retryAt)
+
+						return ctrl.Result{RequeueAfter: requeueAfter(retryAt)}, nil
 					}
 
 					return ctrl.Result{}, err
 				}
 
-				registrationTimeout := 15 * time.Minute
+				// The same timeout as the one after which the runner controller recreates the runner pod.
+				// It's measured from the creation of the current pod like the runner controller does,
+				// so that a runner whose pod has just been recreated is given the same time to register.
+				registrationTimeout := runner.Spec.GetRegistrationTimeout()
 				currentTime := time.Now()
-				registrationDidTimeout := currentTime.Sub(runner.CreationTimestamp.Add(registrationTimeout)) > 0
+
+				var (
+					pod                    corev1.Pod
+					registrationDidTimeout bool
+				)
+
+				if notRegistered {
+					if err := r.Get(ctx, types.NamespacedName{Namespace: runner.Namespace, Name: runner.Name}, &pod); err == nil {
+						registrationDidTimeout = currentTime.Sub(pod.CreationTimestamp.Add(registrationTimeout)) > 0
+					} else if !kerrors.IsNotFound(err) {
+						return ctrl.Result{}, err
+					}
+				}
 
 				if notRegistered && registrationDidTimeout {
 					log.Info(
@@ -163,16 +200,26 @@ func (r *RunnerReplicaSetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 							"Marking the runner for scale down. "+
 							"CAUTION: If you see this a lot, you should investigate the root cause. "+
 							"See https://github.com/summerwind/actions-runner-controller/issues/288",
-						"runnerCreationTimestamp", runner.CreationTimestamp,
+						"runnerName", runner.Name,
+						"podCreationTimestamp", pod.CreationTimestamp,
 						"currentTime", currentTime,
 						"configuredRegistrationTimeout", registrationTimeout,
 					)
 
+					metrics.IncRunnerRegistrationTimeouts(runner.Namespace, metrics.RegistrationTimeoutActionDeleteRunner)
+
+					unregistered[runner.Name] = true
+					notBusy = append(notBusy, runner)
+				} else if notRegistered && getScaleDownPolicy(&rs) == v1alpha1.ScaleDownPolicyUnregisteredFirst {
+					// The runner can't be running a job before registering, and the runner controller drains it
+					// when it has started one by the time it's deleted
+					registering[runner.Name] = true
 					notBusy = append(notBusy, runner)
 				}
 
 				// offline runners should always be a great target for scale down
 				if offline {
+					unregistered[runner.Name] = true
 					notBusy = append(notBusy, runner)
 				}
 			} else if !busy {
@@ -180,6 +227,19 @@ func (r *RunnerReplicaSetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 			}
 		}
 
+		var nodes map[string]string
+
+		if rs.Spec.ScaleDownPolicy == v1alpha1.ScaleDownPolicyEmptiestNodeFirst {
+			nodes, err = r.getRunnerNodes(ctx, myRunners)
+			if err != nil {
+				log.Error(err, "Failed to get nodes of runner pods")
+
+				return ctrl.Result{}, err
+			}
+		}
+
+		sortRunnersForScaleDown(notBusy, rs.Spec.ScaleDownPolicy, unregistered, registering, nodes)
+
 		if len(notBusy) < n {
 			n = len(notBusy)
 		}
@@ -192,7 +252,7 @@ func (r *RunnerReplicaSetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 			}
 
 			r.Recorder.Event(&rs, corev1.EventTypeNormal, "RunnerDeleted", fmt.Sprintf("Deleted runner '%s'", notBusy[i].Name))
-			log.Info("Deleted runner", "runnerreplicaset", rs.ObjectMeta.Name)
+			log.Info("Deleted runner", "runnerreplicaset", rs.ObjectMeta.Name, "runner", notBusy[i].Name, "scaleDownPolicy", rs.Spec.ScaleDownPolicy)
 		}
 	} else if desired > available {
 		n := desired - available
@@ -213,11 +273,23 @@ func (r *RunnerReplicaSetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 		}
 	}
 
-	if rs.Status.AvailableReplicas != available || rs.Status.ReadyReplicas != ready {
-		updated := rs.DeepCopy()
-		updated.Status.AvailableReplicas = available
-		updated.Status.ReadyReplicas = ready
+	updated := rs.DeepCopy()
+	updated.Status.AvailableReplicas = available
+	updated.Status.ReadyReplicas = ready
+	updated.Status.ObservedGeneration = rs.Generation
 
+	// The numbers of registered and busy runners are kept as-is when GitHub API is unavailable,
+	// as they are only informational
+	if registered, busy, err := r.countRegisteredRunners(ctx, log, myRunners); err != nil {
+		log.Error(err, "Failed to count runners registered to GitHub")
+	} else {
+		updated.Status.RegisteredReplicas = registered
+		updated.Status.BusyReplicas = busy
+	}
+
+	setRunnerReplicaSetConditions(&updated.Status, desired, failed, time.Now())
+
+	if !equality.Semantic.DeepEqual(rs.Status, updated.Status) {
 		if err := r.Status().Update(ctx, updated); err != nil {
 			log.Info("Failed to update status. Retrying immediately", "error", err.Error())
 			return ctrl.Result{
@@ -229,6 +301,63 @@ func (r *RunnerReplicaSetReconciler) Reconcile(req ctrl.Request) (ctrl.Result, e
 	return ctrl.Result{}, nil
 }
 
+// getRunnerNodes returns the names of the nodes the pods of the runners are scheduled to, keyed by the names of the runners.
+func (r *RunnerReplicaSetReconciler) getRunnerNodes(ctx context.Context, runners []v1alpha1.Runner) (map[string]string, error) {
+	nodes := map[string]string{}
+
+	for _, runner := range runners {
+		var pod corev1.Pod
+		if err := r.Get(ctx, types.NamespacedName{Namespace: runner.Namespace, Name: runner.Name}, &pod); err != nil {
+			if kerrors.IsNotFound(err) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		nodes[runner.Name] = pod.Spec.NodeName
+	}
+
+	return nodes, nil
+}
+
+// countRegisteredRunners returns the number of the runners registered to GitHub and online,
+// and the number of busy ones among them.
+func (r *RunnerReplicaSetReconciler) countRegisteredRunners(ctx context.Context, log logr.Logger, runners []v1alpha1.Runner) (int, int, error) {
+	var registered, busy int
+
+	for _, runner := range runners {
+		if !runner.ObjectMeta.DeletionTimestamp.IsZero() {
+			continue
+		}
+
+		ghc, err := r.GitHubClient.InitForRunner(ctx, &runner)
+		if err != nil {
+			log.Error(err, "Failed to get GitHub client for runner. Not counting it as registered", "runnerName", runner.Name)
+			continue
+		}
+
+		isBusy, err := ghc.IsRunnerBusy(ctx, runner.Spec.Enterprise, runner.Spec.Organization, runner.Spec.Repository, runner.Name)
+		if err != nil {
+			var notFoundException *github.RunnerNotFound
+			var offlineException *github.RunnerOffline
+			if errors.As(err, &notFoundException) || errors.As(err, &offlineException) {
+				continue
+			}
+
+			return 0, 0, err
+		}
+
+		registered++
+
+		if isBusy {
+			busy++
+		}
+	}
+
+	return registered, busy, nil
+}
+
 func (r *RunnerReplicaSetReconciler) newRunner(rs v1alpha1.RunnerReplicaSet) (v1alpha1.Runner, error) {
 	objectMeta := rs.Spec.Template.ObjectMeta.DeepCopy()
 
@@ -0,0 +1,88 @@
+package controllers
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+)
+
+// sortRunnersForScaleDown sorts the idle runners in the order they are deleted on scale down.
+//
+// Runners with lower deletion costs come first regardless of the policy.
+// unregistered is the set of the names of the runners that failed to register to GitHub or are offline,
+// registering is the set of the names of the runners that are yet to register within the registration timeout,
+// and nodes maps the names of all the runners of the runnerreplicaset to the nodes their pods are scheduled to.
+func sortRunnersForScaleDown(runners []v1alpha1.Runner, policy string, unregistered, registering map[string]bool, nodes map[string]string) {
+	runnersOnNode := map[string]int{}
+	for _, node := range nodes {
+		runnersOnNode[node]++
+	}
+
+	if policy == "" {
+		policy = v1alpha1.ScaleDownPolicyUnregisteredFirst
+	}
+
+	sort.SliceStable(runners, func(i, j int) bool {
+		a, b := &runners[i], &runners[j]
+
+		if ca, cb := getDeletionCost(a), getDeletionCost(b); ca != cb {
+			return ca < cb
+		}
+
+		switch policy {
+		case v1alpha1.ScaleDownPolicyOldestFirst:
+			if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+				return a.CreationTimestamp.Before(&b.CreationTimestamp)
+			}
+		case v1alpha1.ScaleDownPolicyEmptiestNodeFirst:
+			// Runners whose pods aren't scheduled yet free no node, but they don't run jobs either
+			na, nb := nodes[a.Name], nodes[b.Name]
+			if (na == "") != (nb == "") {
+				return na == ""
+			}
+
+			if runnersOnNode[na] != runnersOnNode[nb] {
+				return runnersOnNode[na] < runnersOnNode[nb]
+			}
+
+			// Runners on the same node are deleted one after another
+			if na != nb {
+				return na < nb
+			}
+		case v1alpha1.ScaleDownPolicyUnregisteredFirst:
+			if unregistered[a.Name] != unregistered[b.Name] {
+				return unregistered[a.Name]
+			}
+
+			if registering[a.Name] != registering[b.Name] {
+				return registering[a.Name]
+			}
+		}
+
+		if !a.CreationTimestamp.Equal(&b.CreationTimestamp) {
+			return b.CreationTimestamp.Before(&a.CreationTimestamp)
+		}
+
+		return a.Name < b.Name
+	})
+}
+
+// getScaleDownPolicy returns the scale down policy of the runnerreplicaset, or the default one when it's not set.
+func getScaleDownPolicy(rs *v1alpha1.RunnerReplicaSet) string {
+	if rs.Spec.ScaleDownPolicy == "" {
+		return v1alpha1.ScaleDownPolicyUnregisteredFirst
+	}
+
+	return rs.Spec.ScaleDownPolicy
+}
+
+// getDeletionCost returns the deletion cost of the runner, or 0 when it's not set or invalid.
+func getDeletionCost(runner *v1alpha1.Runner) int64 {
+	cost, err := strconv.ParseInt(runner.Annotations[v1alpha1.AnnotationKeyDeletionCost], 10, 64)
+	if err != nil {
+		return 0
+	}
+
+	return cost
+}
@@ -0,0 +1,283 @@
+package controllers
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	corev1 "k8s.io/api/core/v1"
+	kerrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	kfake "sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	"github.com/summerwind/actions-runner-controller/github"
+	"github.com/summerwind/actions-runner-controller/github/fake"
+)
+
+func TestSortRunnersForScaleDown(t *testing.T) {
+	now := time.Now()
+
+	newRunner := func(name string, age time.Duration, annotations map[string]string) v1alpha1.Runner {
+		return v1alpha1.Runner{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              name,
+				CreationTimestamp: metav1.Time{Time: now.Add(-age)},
+				Annotations:       annotations,
+			},
+		}
+	}
+
+	unregistered := map[string]bool{"offline": true}
+
+	registering := map[string]bool{"registering": true}
+
+	nodes := map[string]string{
+		"old":     "node1",
+		"offline": "node1",
+		"new":     "node2",
+		"middle":  "node1",
+		"busy":    "node2",
+	}
+
+	testcases := []struct {
+		policy string
+		want   []string
+	}{
+		{policy: "", want: []string{"offline", "registering", "new", "middle", "old"}},
+		{policy: v1alpha1.ScaleDownPolicyUnregisteredFirst, want: []string{"offline", "registering", "new", "middle", "old"}},
+		{policy: v1alpha1.ScaleDownPolicyNewestFirst, want: []string{"new", "registering", "middle", "offline", "old"}},
+		{policy: v1alpha1.ScaleDownPolicyOldestFirst, want: []string{"old", "offline", "middle", "registering", "new"}},
+		{policy: v1alpha1.ScaleDownPolicyEmptiestNodeFirst, want: []string{"registering", "new", "middle", "offline", "old"}},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.policy, func(t *testing.T) {
+			runners := []v1alpha1.Runner{
+				newRunner("old", 3*time.Hour, nil),
+				newRunner("offline", 2*time.Hour, nil),
+				newRunner("new", time.Minute, nil),
+				newRunner("registering", 2*time.Minute, nil),
+				newRunner("middle", time.Hour, nil),
+			}
+
+			sortRunnersForScaleDown(runners, tc.policy, unregistered, registering, nodes)
+
+			var got []string
+			for _, r := range runners {
+				got = append(got, r.Name)
+			}
+
+			if d := cmp.Diff(tc.want, got); d != "" {
+				t.Errorf("unexpected order: (-want +got)\n%s", d)
+			}
+		})
+	}
+
+	t.Run("deletion cost", func(t *testing.T) {
+		runners := []v1alpha1.Runner{
+			newRunner("new", time.Minute, map[string]string{v1alpha1.AnnotationKeyDeletionCost: "100"}),
+			newRunner("invalid", time.Hour, map[string]string{v1alpha1.AnnotationKeyDeletionCost: "foo"}),
+			newRunner("old", 3*time.Hour, map[string]string{v1alpha1.AnnotationKeyDeletionCost: "-1"}),
+		}
+
+		sortRunnersForScaleDown(runners, v1alpha1.ScaleDownPolicyNewestFirst, nil, nil, nil)
+
+		var got []string
+		for _, r := range runners {
+			got = append(got, r.Name)
+		}
+
+		if d := cmp.Diff([]string{"old", "invalid", "new"}, got); d != "" {
+			t.Errorf("unexpected order: (-want +got)\n%s", d)
+		}
+	})
+}
+
+func TestReconcile_ScaleDownRegisteringRunners(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	server := fake.NewServer(fake.WithListRunnersResponse(http.StatusOK, fake.RunnersListBody))
+	defer server.Close()
+
+	testcases := []struct {
+		policy      string
+		wantDeleted bool
+	}{
+		{policy: "", wantDeleted: true},
+		{policy: v1alpha1.ScaleDownPolicyNewestFirst, wantDeleted: false},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.policy, func(t *testing.T) {
+			replicas := 0
+
+			rs := &v1alpha1.RunnerReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "example",
+					Namespace: "default",
+					UID:       "example-uid",
+				},
+				Spec: v1alpha1.RunnerReplicaSetSpec{
+					Replicas: &replicas,
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"foo": "bar"},
+					},
+					ScaleDownPolicy: tc.policy,
+				},
+			}
+
+			// The runner is yet to register to GitHub, within the registration timeout
+			runner := &v1alpha1.Runner{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "example-registering",
+					Namespace:         "default",
+					Labels:            map[string]string{"foo": "bar"},
+					CreationTimestamp: metav1.Now(),
+				},
+				Spec: v1alpha1.RunnerSpec{
+					Repository: "test/valid",
+				},
+			}
+
+			if err := ctrl.SetControllerReference(rs, runner, scheme); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			client := kfake.NewFakeClientWithScheme(scheme, rs, runner)
+
+			r := &RunnerReplicaSetReconciler{
+				Client:       client,
+				Log:          zap.New(),
+				Recorder:     record.NewFakeRecorder(10),
+				Scheme:       scheme,
+				GitHubClient: NewMultiGitHubClient(client, newGithubClient(server), github.Config{}),
+			}
+
+			if _, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: rs.Name}}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err := client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: runner.Name}, &v1alpha1.Runner{})
+			if deleted := kerrors.IsNotFound(err); deleted != tc.wantDeleted {
+				t.Errorf("unexpected deletion of the registering runner: want %v, got %v (%v)", tc.wantDeleted, deleted, err)
+			}
+		})
+	}
+}
+
+func TestReconcile_ScaleDownRunnersAfterRegistrationTimeout(t *testing.T) {
+	scheme := runtime.NewScheme()
+	_ = clientgoscheme.AddToScheme(scheme)
+	_ = v1alpha1.AddToScheme(scheme)
+
+	server := fake.NewServer(fake.WithListRunnersResponse(http.StatusOK, fake.RunnersListBody))
+	defer server.Close()
+
+	now := time.Now()
+
+	testcases := []struct {
+		name        string
+		podAge      *time.Duration
+		wantDeleted bool
+	}{
+		{
+			name:        "pod timed out",
+			podAge:      durationPtr(2 * time.Hour),
+			wantDeleted: true,
+		},
+		{
+			name:        "pod recreated",
+			podAge:      durationPtr(time.Minute),
+			wantDeleted: false,
+		},
+		{
+			name:        "pod being recreated",
+			wantDeleted: false,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			replicas := 0
+
+			// The policy that doesn't delete the runners that are still registering
+			rs := &v1alpha1.RunnerReplicaSet{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:      "example",
+					Namespace: "default",
+					UID:       "example-uid",
+				},
+				Spec: v1alpha1.RunnerReplicaSetSpec{
+					Replicas: &replicas,
+					Selector: &metav1.LabelSelector{
+						MatchLabels: map[string]string{"foo": "bar"},
+					},
+					ScaleDownPolicy: v1alpha1.ScaleDownPolicyNewestFirst,
+				},
+			}
+
+			// The runner was created long before the registration timeout, but its pod may have been recreated since then
+			runner := &v1alpha1.Runner{
+				ObjectMeta: metav1.ObjectMeta{
+					Name:              "example-unregistered",
+					Namespace:         "default",
+					Labels:            map[string]string{"foo": "bar"},
+					CreationTimestamp: metav1.NewTime(now.Add(-3 * time.Hour)),
+				},
+				Spec: v1alpha1.RunnerSpec{
+					Repository: "test/valid",
+				},
+			}
+
+			if err := ctrl.SetControllerReference(rs, runner, scheme); err != nil {
+				t.Fatalf("%v", err)
+			}
+
+			objs := []runtime.Object{rs, runner}
+
+			if tc.podAge != nil {
+				objs = append(objs, &corev1.Pod{
+					ObjectMeta: metav1.ObjectMeta{
+						Name:              runner.Name,
+						Namespace:         runner.Namespace,
+						CreationTimestamp: metav1.NewTime(now.Add(-*tc.podAge)),
+					},
+				})
+			}
+
+			client := kfake.NewFakeClientWithScheme(scheme, objs...)
+
+			r := &RunnerReplicaSetReconciler{
+				Client:       client,
+				Log:          zap.New(),
+				Recorder:     record.NewFakeRecorder(10),
+				Scheme:       scheme,
+				GitHubClient: NewMultiGitHubClient(client, newGithubClient(server), github.Config{}),
+			}
+
+			if _, err := r.Reconcile(ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: rs.Name}}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			err := client.Get(context.Background(), types.NamespacedName{Namespace: "default", Name: runner.Name}, &v1alpha1.Runner{})
+			if deleted := kerrors.IsNotFound(err); deleted != tc.wantDeleted {
+				t.Errorf("unexpected deletion of the unregistered runner: want %v, got %v (%v)", tc.wantDeleted, deleted, err)
+			}
+		})
+	}
+}
+
+func durationPtr(d time.Duration) *time.Duration {
+	return &d
+}
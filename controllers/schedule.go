@@ -0,0 +1,89 @@
+package controllers
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+)
+
+// getScheduledOverride returns the first scheduled override whose window is active at the time, if any.
+// It also returns the earliest time after now at which any of the windows starts or ends,
+// so that the autoscaler can be reconciled right at that time.
+func getScheduledOverride(overrides []v1alpha1.ScheduledOverride, now time.Time) (*v1alpha1.ScheduledOverride, *time.Time, error) {
+	var (
+		active *v1alpha1.ScheduledOverride
+		next   *time.Time
+	)
+
+	for i := range overrides {
+		o := overrides[i]
+
+		start, end, err := getScheduledOverrideWindow(o, now)
+		if err != nil {
+			return nil, nil, fmt.Errorf("validating scheduled override %d: %w", i, err)
+		}
+
+		transition := start
+		if !start.After(now) {
+			transition = end
+
+			if active == nil {
+				active = &o
+			}
+		}
+
+		if next == nil || transition.Before(*next) {
+			next = &transition
+		}
+	}
+
+	return active, next, nil
+}
+
+// getScheduledOverrideWindow returns the window of the scheduled override that is active at the time,
+// or the next window when none is active.
+func getScheduledOverrideWindow(o v1alpha1.ScheduledOverride, now time.Time) (time.Time, time.Time, error) {
+	loc := time.UTC
+
+	if o.TimeZone != "" {
+		var err error
+
+		loc, err = time.LoadLocation(o.TimeZone)
+		if err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("loading time zone %q: %w", o.TimeZone, err)
+		}
+	}
+
+	schedule, err := cron.ParseStandard(o.Schedule)
+	if err != nil {
+		return time.Time{}, time.Time{}, fmt.Errorf("parsing schedule %q: %w", o.Schedule, err)
+	}
+
+	duration := o.Duration.Duration
+	if duration <= 0 {
+		return time.Time{}, time.Time{}, fmt.Errorf("duration must be positive, but was %s", duration)
+	}
+
+	// The earliest start time within the last duration is the start of the active window.
+	// If there's none, it's the start of the next window.
+	start := schedule.Next(now.In(loc).Add(-duration))
+
+	return start, start.Add(duration), nil
+}
+
+// applyScheduledOverride overrides MinReplicas and MaxReplicas of the autoscaler with the ones of the scheduled override.
+func applyScheduledOverride(hra *v1alpha1.HorizontalRunnerAutoscaler, o *v1alpha1.ScheduledOverride) {
+	if o == nil {
+		return
+	}
+
+	if o.MinReplicas != nil {
+		hra.Spec.MinReplicas = o.MinReplicas
+	}
+
+	if o.MaxReplicas != nil {
+		hra.Spec.MaxReplicas = o.MaxReplicas
+	}
+}
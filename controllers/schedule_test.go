@@ -0,0 +1,146 @@
+package controllers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/summerwind/actions-runner-controller/api/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestGetScheduledOverride(t *testing.T) {
+	intPtr := func(v int) *int {
+		return &v
+	}
+
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	weekdays := v1alpha1.ScheduledOverride{
+		Schedule:    "0 8 * * 1-5",
+		Duration:    metav1.Duration{Duration: 11 * time.Hour},
+		TimeZone:    "Europe/Berlin",
+		MinReplicas: intPtr(20),
+	}
+
+	weekends := v1alpha1.ScheduledOverride{
+		Schedule:    "0 0 * * 6",
+		Duration:    metav1.Duration{Duration: 48 * time.Hour},
+		TimeZone:    "Europe/Berlin",
+		MinReplicas: intPtr(0),
+		MaxReplicas: intPtr(0),
+	}
+
+	everyDay := v1alpha1.ScheduledOverride{
+		Schedule:    "0 0 * * *",
+		Duration:    metav1.Duration{Duration: 24 * time.Hour},
+		MinReplicas: intPtr(1),
+	}
+
+	testcases := []struct {
+		name      string
+		overrides []v1alpha1.ScheduledOverride
+		now       time.Time
+		want      *v1alpha1.ScheduledOverride
+		wantNext  time.Time
+		err       string
+	}{
+		{
+			name:      "weekday business hours",
+			overrides: []v1alpha1.ScheduledOverride{weekdays, weekends},
+			now:       time.Date(2021, 3, 3, 10, 0, 0, 0, berlin),
+			want:      &weekdays,
+			wantNext:  time.Date(2021, 3, 3, 19, 0, 0, 0, berlin),
+		},
+		{
+			name:      "weekday at the start of business hours",
+			overrides: []v1alpha1.ScheduledOverride{weekdays, weekends},
+			now:       time.Date(2021, 3, 3, 8, 0, 0, 0, berlin),
+			want:      &weekdays,
+			wantNext:  time.Date(2021, 3, 3, 19, 0, 0, 0, berlin),
+		},
+		{
+			name:      "weekday at the end of business hours",
+			overrides: []v1alpha1.ScheduledOverride{weekdays, weekends},
+			now:       time.Date(2021, 3, 3, 19, 0, 0, 0, berlin),
+			want:      nil,
+			wantNext:  time.Date(2021, 3, 4, 8, 0, 0, 0, berlin),
+		},
+		{
+			name:      "weekend",
+			overrides: []v1alpha1.ScheduledOverride{weekdays, weekends},
+			now:       time.Date(2021, 3, 6, 12, 0, 0, 0, berlin),
+			want:      &weekends,
+			wantNext:  time.Date(2021, 3, 8, 0, 0, 0, 0, berlin),
+		},
+		{
+			name:      "business hours right after the switch to daylight saving time",
+			overrides: []v1alpha1.ScheduledOverride{weekdays, weekends},
+			now:       time.Date(2021, 3, 29, 6, 30, 0, 0, time.UTC),
+			want:      &weekdays,
+			wantNext:  time.Date(2021, 3, 29, 19, 0, 0, 0, berlin),
+		},
+		{
+			name:      "the first active override wins",
+			overrides: []v1alpha1.ScheduledOverride{everyDay, weekdays},
+			now:       time.Date(2021, 3, 3, 10, 0, 0, 0, time.UTC),
+			want:      &everyDay,
+			wantNext:  time.Date(2021, 3, 3, 18, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "invalid time zone",
+			overrides: []v1alpha1.ScheduledOverride{
+				{Schedule: "0 8 * * 1-5", Duration: metav1.Duration{Duration: time.Hour}, TimeZone: "Europe/Nowhere"},
+			},
+			now: time.Date(2021, 3, 3, 10, 0, 0, 0, time.UTC),
+			err: `validating scheduled override 0: loading time zone "Europe/Nowhere": unknown time zone Europe/Nowhere`,
+		},
+		{
+			name: "invalid schedule",
+			overrides: []v1alpha1.ScheduledOverride{
+				weekdays,
+				{Schedule: "every weekday", Duration: metav1.Duration{Duration: time.Hour}},
+			},
+			now: time.Date(2021, 3, 3, 10, 0, 0, 0, time.UTC),
+			err: `validating scheduled override 1: parsing schedule "every weekday": expected exactly 5 fields, found 2: [every weekday]`,
+		},
+		{
+			name: "missing duration",
+			overrides: []v1alpha1.ScheduledOverride{
+				{Schedule: "0 8 * * 1-5"},
+			},
+			now: time.Date(2021, 3, 3, 10, 0, 0, 0, time.UTC),
+			err: `validating scheduled override 0: duration must be positive, but was 0s`,
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, next, err := getScheduledOverride(tc.overrides, tc.now)
+			if err != nil {
+				if tc.err == "" {
+					t.Fatalf("unexpected error: expected none, got %v", err)
+				} else if err.Error() != tc.err {
+					t.Fatalf("unexpected error: expected %v, got %v", tc.err, err)
+				}
+				return
+			} else if tc.err != "" {
+				t.Fatalf("expected error %q, got none", tc.err)
+			}
+
+			if tc.want == nil {
+				if got != nil {
+					t.Errorf("unexpected scheduled override: want none, got %+v", *got)
+				}
+			} else if got == nil || got.Schedule != tc.want.Schedule {
+				t.Errorf("unexpected scheduled override: want %+v, got %+v", *tc.want, got)
+			}
+
+			if next == nil || !next.Equal(tc.wantNext) {
+				t.Errorf("unexpected next transition: want %s, got %v", tc.wantNext, next)
+			}
+		})
+	}
+}
@@ -1,5 +1,12 @@
 package controllers
 
+import "time"
+
+// minRequeueDelay is the shortest delay requeueAfter returns.
+// controller-runtime doesn't requeue at all on a non-positive RequeueAfter without an error,
+// which would leave the object unreconciled until the next change.
+const minRequeueDelay = time.Second
+
 func filterLabels(labels map[string]string, filter string) map[string]string {
 	filtered := map[string]string{}
 
@@ -11,3 +18,15 @@ func filterLabels(labels map[string]string, filter string) map[string]string {
 
 	return filtered
 }
+
+// requeueAfter returns the delay to requeue the object with to retry at t,
+// which is at least minRequeueDelay even when t has already passed.
+//
+// It's used on GitHub API rate limits with a nil error, as retrying before the rate limit resets only burns the quota.
+func requeueAfter(t time.Time) time.Duration {
+	if d := time.Until(t); d > minRequeueDelay {
+		return d
+	}
+
+	return minRequeueDelay
+}
@@ -3,6 +3,7 @@ package controllers
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func Test_filterLabels(t *testing.T) {
@@ -32,3 +33,19 @@ func Test_filterLabels(t *testing.T) {
 		})
 	}
 }
+
+func Test_requeueAfter(t *testing.T) {
+	now := time.Now()
+
+	if got := requeueAfter(now.Add(-time.Minute)); got != minRequeueDelay {
+		t.Errorf("unexpected delay for the past time: want %s, got %s", minRequeueDelay, got)
+	}
+
+	if got := requeueAfter(now); got != minRequeueDelay {
+		t.Errorf("unexpected delay for now: want %s, got %s", minRequeueDelay, got)
+	}
+
+	if got := requeueAfter(now.Add(time.Hour)); got < 59*time.Minute || got > time.Hour {
+		t.Errorf("unexpected delay for the future time: want about 1h, got %s", got)
+	}
+}
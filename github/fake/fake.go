@@ -199,6 +199,9 @@ func NewServer(opts ...Option) *httptest.Server {
 			Body:   "",
 		},
 
+		// For auto-scaling organizational runners based on the repositories matching the patterns
+		"/orgs/test/repos": config.FixedResponses.ListOrganizationRepositories,
+
 		// For auto-scaling based on the number of queued(pending) workflow runs
 		"/repos/test/valid/actions/runs": config.FixedResponses.ListRepositoryWorkflowRuns,
 
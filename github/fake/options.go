@@ -6,6 +6,8 @@ type FixedResponses struct {
 	ListRepositoryWorkflowRuns *Handler
 	ListWorkflowJobs           *MapHandler
 	ListRunners                http.Handler
+
+	ListOrganizationRepositories *Handler
 }
 
 type Option func(*ServerConfig)
@@ -41,6 +43,15 @@ func WithListRunnersResponse(status int, body string) Option {
 	}
 }
 
+func WithListOrganizationRepositoriesResponse(status int, body string) Option {
+	return func(c *ServerConfig) {
+		c.FixedResponses.ListOrganizationRepositories = &Handler{
+			Status: status,
+			Body:   body,
+		}
+	}
+}
+
 func WithFixedResponses(responses *FixedResponses) Option {
 	return func(c *ServerConfig) {
 		c.FixedResponses = responses
@@ -6,12 +6,14 @@ import (
 	"net/http"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"github.com/bradleyfalzon/ghinstallation"
 	"github.com/google/go-github/v33/github"
+	"github.com/summerwind/actions-runner-controller/github/httpcache"
 	"github.com/summerwind/actions-runner-controller/github/metrics"
 	"golang.org/x/oauth2"
 )
@@ -23,47 +25,104 @@ type Config struct {
 	AppInstallationID int64  `split_words:"true"`
 	AppPrivateKey     string `split_words:"true"`
 	Token             string
+
+	// RunnerListCacheDuration is how long the runners listed per enterprise, organization or repository are reused.
+	// Set to zero to list the runners on every call.
+	RunnerListCacheDuration time.Duration `split_words:"true" default:"10s"`
+
+	// RateLimitReserve is the number of remaining requests in the rate limit window that are kept for
+	// creating registration tokens and removing runners. The other calls fail with RateLimitedError
+	// until the rate limit resets once the remaining requests drop to the reserve.
+	RateLimitReserve int `split_words:"true" default:"100"`
+
+	// ConditionalRequests enables sending GET requests with the ETags of the previous responses, kept in memory,
+	// so that the responses that haven't changed are answered with 304 Not Modified without counting against the rate limit.
+	ConditionalRequests bool `split_words:"true"`
 }
 
 // Client wraps GitHub client with some additional
 type Client struct {
 	*github.Client
 	regTokens map[string]*github.RegistrationToken
+	regScopes map[string]registrationTokenScope
 	mu        sync.Mutex
+	runners   *runnerListCache
+	// apps is set when the client authenticates as the GitHub App itself,
+	// so that each request is made as the installation for its organization or repository.
+	apps *appInstallations
 	// GithubBaseURL to Github without API suffix.
 	GithubBaseURL string
 }
 
 // NewClient creates a Github Client
+//
+// When AppInstallationID is omitted for GitHub App authentication, the client authenticates as the App itself
+// and looks up the installation for the organization or repository of each request.
 func (c *Config) NewClient() (*Client, error) {
 	var transport http.RoundTripper
+	// installation is the label of the rate limit metrics, which is left empty for PAT
+	var installation string
+	var apps *appInstallations
 	if len(c.Token) > 0 {
 		transport = oauth2.NewClient(context.Background(), oauth2.StaticTokenSource(&oauth2.Token{AccessToken: c.Token})).Transport
 	} else {
-		var tr *ghinstallation.Transport
+		atr, err := c.newAppsTransport()
+		if err != nil {
+			return nil, err
+		}
 
-		if _, err := os.Stat(c.AppPrivateKey); err == nil {
-			tr, err = ghinstallation.NewKeyFromFile(http.DefaultTransport, c.AppID, c.AppInstallationID, c.AppPrivateKey)
-			if err != nil {
-				return nil, fmt.Errorf("authentication failed: using private key at %s: %v", c.AppPrivateKey, err)
-			}
+		if c.AppInstallationID == 0 {
+			apps = newAppInstallations(*c, atr)
+			transport = atr
 		} else {
-			tr, err = ghinstallation.New(http.DefaultTransport, c.AppID, c.AppInstallationID, []byte(c.AppPrivateKey))
-			if err != nil {
-				return nil, fmt.Errorf("authentication failed: using private key of size %d (%s...): %v", len(c.AppPrivateKey), strings.Split(c.AppPrivateKey, "\n")[0], err)
-			}
+			transport = ghinstallation.NewFromAppsTransport(atr, c.AppInstallationID)
+			installation = strconv.FormatInt(c.AppInstallationID, 10)
 		}
+	}
 
-		if len(c.EnterpriseURL) > 0 {
-			githubAPIURL, err := getEnterpriseApiUrl(c.EnterpriseURL)
-			if err != nil {
-				return nil, fmt.Errorf("enterprise url incorrect: %v", err)
-			}
-			tr.BaseURL = githubAPIURL
+	client, err := c.newClient(transport, installation)
+	if err != nil {
+		return nil, err
+	}
+
+	client.apps = apps
+
+	return client, nil
+}
+
+// newAppsTransport creates the transport to authenticate as the GitHub App.
+func (c *Config) newAppsTransport() (*ghinstallation.AppsTransport, error) {
+	var atr *ghinstallation.AppsTransport
+
+	if _, err := os.Stat(c.AppPrivateKey); err == nil {
+		atr, err = ghinstallation.NewAppsTransportKeyFromFile(http.DefaultTransport, c.AppID, c.AppPrivateKey)
+		if err != nil {
+			return nil, fmt.Errorf("authentication failed: using private key at %s: %v", c.AppPrivateKey, err)
+		}
+	} else {
+		atr, err = ghinstallation.NewAppsTransport(http.DefaultTransport, c.AppID, []byte(c.AppPrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("authentication failed: using private key of size %d (%s...): %v", len(c.AppPrivateKey), strings.Split(c.AppPrivateKey, "\n")[0], err)
+		}
+	}
+
+	if len(c.EnterpriseURL) > 0 {
+		githubAPIURL, err := getEnterpriseApiUrl(c.EnterpriseURL)
+		if err != nil {
+			return nil, fmt.Errorf("enterprise url incorrect: %v", err)
 		}
-		transport = tr
+		atr.BaseURL = githubAPIURL
+	}
+
+	return atr, nil
+}
+
+func (c *Config) newClient(transport http.RoundTripper, installation string) (*Client, error) {
+	transport = metrics.Transport{Transport: transport, Installation: installation}
+	transport = &throttleTransport{Transport: transport, reserve: c.RateLimitReserve}
+	if c.ConditionalRequests {
+		transport = &httpcache.Transport{Transport: transport}
 	}
-	transport = metrics.Transport{Transport: transport}
 	httpClient := &http.Client{Transport: transport}
 
 	var client *github.Client
@@ -83,52 +142,54 @@ func (c *Config) NewClient() (*Client, error) {
 	return &Client{
 		Client:        client,
 		regTokens:     map[string]*github.RegistrationToken{},
+		regScopes:     map[string]registrationTokenScope{},
 		mu:            sync.Mutex{},
+		runners:       newRunnerListCache(c.RunnerListCacheDuration),
 		GithubBaseURL: githubBaseURL,
 	}, nil
 }
 
 // GetRegistrationToken returns a registration token tied with the name of repository and runner.
+// The token is shared across the runners of the same enterprise, organization or repository.
 func (c *Client) GetRegistrationToken(ctx context.Context, enterprise, org, repo, name string) (*github.RegistrationToken, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	key := getRegistrationKey(org, repo, enterprise)
-	rt, ok := c.regTokens[key]
-
-	// we like to give runners a chance that are just starting up and may miss the expiration date by a bit
-	runnerStartupTimeout := 3 * time.Minute
-
-	if ok && rt.GetExpiresAt().After(time.Now().Add(runnerStartupTimeout)) {
-		return rt, nil
+	if c.apps != nil {
+		ic, err := c.installationClient(ctx, enterprise, org, repo)
+		if err != nil {
+			return nil, err
+		}
+		return ic.GetRegistrationToken(ctx, enterprise, org, repo, name)
 	}
 
-	enterprise, owner, repo, err := getEnterpriseOrganisationAndRepo(enterprise, org, repo)
-
-	if err != nil {
-		return rt, err
+	if _, _, _, err := getEnterpriseOrganisationAndRepo(enterprise, org, repo); err != nil {
+		return nil, err
 	}
 
-	rt, res, err := c.createRegistrationToken(ctx, enterprise, owner, repo)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed to create registration token: %v", err)
-	}
+	key := getRegistrationKey(org, repo, enterprise)
 
-	if res.StatusCode != 201 {
-		return nil, fmt.Errorf("unexpected status: %d", res.StatusCode)
+	c.mu.Lock()
+	scope, ok := c.regScopes[key]
+	if !ok {
+		scope = registrationTokenScope{enterprise: enterprise, org: org, repo: repo, mu: &sync.Mutex{}}
 	}
+	scope.lastUsed = time.Now()
+	c.regScopes[key] = scope
+	c.mu.Unlock()
 
-	c.regTokens[key] = rt
-	go func() {
-		c.cleanup()
-	}()
-
-	return rt, nil
+	return c.refreshRegistrationToken(ctx, key, scope, runnerStartupTimeout)
 }
 
 // RemoveRunner removes a runner with specified runner ID from repository.
 func (c *Client) RemoveRunner(ctx context.Context, enterprise, org, repo string, runnerID int64) error {
+	if c.apps != nil {
+		ic, err := c.installationClient(ctx, enterprise, org, repo)
+		if err != nil {
+			return err
+		}
+		return ic.RemoveRunner(ctx, enterprise, org, repo, runnerID)
+	}
+
+	defer c.runners.invalidate(getRegistrationKey(org, repo, enterprise))
+
 	enterprise, owner, repo, err := getEnterpriseOrganisationAndRepo(enterprise, org, repo)
 
 	if err != nil {
@@ -149,7 +210,23 @@ func (c *Client) RemoveRunner(ctx context.Context, enterprise, org, repo string,
 }
 
 // ListRunners returns a list of runners of specified owner/repository name.
+// The list is reused for RunnerListCacheDuration, so that checking each runner of a large pool costs a single listing.
 func (c *Client) ListRunners(ctx context.Context, enterprise, org, repo string) ([]*github.Runner, error) {
+	if c.apps != nil {
+		ic, err := c.installationClient(ctx, enterprise, org, repo)
+		if err != nil {
+			return nil, err
+		}
+		return ic.ListRunners(ctx, enterprise, org, repo)
+	}
+
+	key := getRegistrationKey(org, repo, enterprise)
+	now := time.Now()
+
+	if runners, ok := c.runners.get(key, now); ok {
+		return runners, nil
+	}
+
 	enterprise, owner, repo, err := getEnterpriseOrganisationAndRepo(enterprise, org, repo)
 
 	if err != nil {
@@ -173,6 +250,8 @@ func (c *Client) ListRunners(ctx context.Context, enterprise, org, repo string)
 		opts.Page = res.NextPage
 	}
 
+	c.runners.set(key, runners, now)
+
 	return runners, nil
 }
 
@@ -222,6 +301,14 @@ func (c *Client) listRunners(ctx context.Context, enterprise, org, repo string,
 }
 
 func (c *Client) ListRepositoryWorkflowRuns(ctx context.Context, user string, repoName string) ([]*github.WorkflowRun, error) {
+	if c.apps != nil {
+		ic, err := c.installationClient(ctx, "", "", user+"/"+repoName)
+		if err != nil {
+			return nil, err
+		}
+		return ic.ListRepositoryWorkflowRuns(ctx, user, repoName)
+	}
+
 	queued, err := c.listRepositoryWorkflowRuns(ctx, user, repoName, "queued")
 	if err != nil {
 		return nil, fmt.Errorf("listing queued workflow runs: %w", err)
@@ -240,6 +327,93 @@ func (c *Client) ListRepositoryWorkflowRuns(ctx context.Context, user string, re
 	return workflowRuns, nil
 }
 
+// WorkflowJob is a job of a workflow run.
+// Unlike github.WorkflowJob, it has the labels requested by the job's `runs-on`, which go-github v33 doesn't know about yet.
+type WorkflowJob struct {
+	github.WorkflowJob
+
+	Labels []string `json:"labels,omitempty"`
+}
+
+// WorkflowJobEvent is triggered when a GitHub Actions workflow job is queued, started or completed.
+//
+// go-github v33 doesn't know about this event yet, hence we parse it on our own.
+// See https://docs.github.com/en/developers/webhooks-and-events/webhooks/webhook-events-and-payloads#workflow_job
+type WorkflowJobEvent struct {
+	WorkflowJob *WorkflowJob `json:"workflow_job,omitempty"`
+
+	Action       *string              `json:"action,omitempty"`
+	Repo         *github.Repository   `json:"repository,omitempty"`
+	Org          *github.Organization `json:"organization,omitempty"`
+	Sender       *github.User         `json:"sender,omitempty"`
+	Installation *github.Installation `json:"installation,omitempty"`
+}
+
+// GetAction returns the Action field if it's non-nil, zero value otherwise.
+func (e *WorkflowJobEvent) GetAction() string {
+	if e == nil || e.Action == nil {
+		return ""
+	}
+
+	return *e.Action
+}
+
+// GetWorkflowJob returns the WorkflowJob field.
+func (e *WorkflowJobEvent) GetWorkflowJob() *WorkflowJob {
+	if e == nil {
+		return nil
+	}
+
+	return e.WorkflowJob
+}
+
+type workflowJobs struct {
+	TotalCount *int           `json:"total_count,omitempty"`
+	Jobs       []*WorkflowJob `json:"jobs,omitempty"`
+}
+
+// ListWorkflowJobs returns all the jobs of the workflow run, including their labels.
+func (c *Client) ListWorkflowJobs(ctx context.Context, user string, repoName string, runID int64) ([]*WorkflowJob, error) {
+	if c.apps != nil {
+		ic, err := c.installationClient(ctx, "", "", user+"/"+repoName)
+		if err != nil {
+			return nil, err
+		}
+		return ic.ListWorkflowJobs(ctx, user, repoName, runID)
+	}
+
+	var jobs []*WorkflowJob
+
+	page := 0
+
+	for {
+		u := fmt.Sprintf("repos/%s/%s/actions/runs/%d/jobs?per_page=100", user, repoName, runID)
+		if page > 0 {
+			u = fmt.Sprintf("%s&page=%d", u, page)
+		}
+
+		req, err := c.Client.NewRequest("GET", u, nil)
+		if err != nil {
+			return nil, err
+		}
+
+		list := new(workflowJobs)
+
+		res, err := c.Client.Do(ctx, req, list)
+		if err != nil {
+			return jobs, fmt.Errorf("failed to list workflow jobs: %w", err)
+		}
+
+		jobs = append(jobs, list.Jobs...)
+		if res.NextPage == 0 {
+			break
+		}
+		page = res.NextPage
+	}
+
+	return jobs, nil
+}
+
 func (c *Client) listRepositoryWorkflowRuns(ctx context.Context, user string, repoName, status string) ([]*github.WorkflowRun, error) {
 	var workflowRuns []*github.WorkflowRun
 
@@ -254,7 +428,7 @@ func (c *Client) listRepositoryWorkflowRuns(ctx context.Context, user string, re
 		list, res, err := c.Client.Actions.ListRepositoryWorkflowRuns(ctx, user, repoName, &opts)
 
 		if err != nil {
-			return workflowRuns, fmt.Errorf("failed to list workflow runs: %v", err)
+			return workflowRuns, fmt.Errorf("failed to list workflow runs: %w", err)
 		}
 
 		workflowRuns = append(workflowRuns, list.WorkflowRuns...)
@@ -267,6 +441,41 @@ func (c *Client) listRepositoryWorkflowRuns(ctx context.Context, user string, re
 	return workflowRuns, nil
 }
 
+// ListOrganizationRepositories returns all the repositories of the organization visible to the client.
+func (c *Client) ListOrganizationRepositories(ctx context.Context, org string) ([]*github.Repository, error) {
+	if c.apps != nil {
+		ic, err := c.installationClient(ctx, "", org, "")
+		if err != nil {
+			return nil, err
+		}
+		return ic.ListOrganizationRepositories(ctx, org)
+	}
+
+	var repos []*github.Repository
+
+	opts := github.RepositoryListByOrgOptions{
+		ListOptions: github.ListOptions{
+			PerPage: 100,
+		},
+	}
+
+	for {
+		list, res, err := c.Client.Repositories.ListByOrg(ctx, org, &opts)
+
+		if err != nil {
+			return repos, fmt.Errorf("failed to list repositories: %w", err)
+		}
+
+		repos = append(repos, list...)
+		if res.NextPage == 0 {
+			break
+		}
+		opts.Page = res.NextPage
+	}
+
+	return repos, nil
+}
+
 // Validates enterprise, organisation and repo arguments. Both are optional, but at least one should be specified
 func getEnterpriseOrganisationAndRepo(enterprise, org, repo string) (string, string, string, error) {
 	if len(repo) > 0 {
@@ -2,8 +2,15 @@ package github
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"sync"
 	"testing"
 	"time"
 
@@ -152,3 +159,279 @@ func TestCleanup(t *testing.T) {
 		t.Errorf("expired token still exists")
 	}
 }
+
+func TestListRunners_Cache(t *testing.T) {
+	var requests int
+
+	listRunners := fake.DefaultListRunnersHandler()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/repos/test/valid/actions/runners" {
+			requests++
+		}
+
+		if req.URL.Path == "/repos/test/valid/actions/runners/1" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		listRunners.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+
+	c := Config{
+		Token:                   "token",
+		RunnerListCacheDuration: time.Minute,
+	}
+	client, err := c.NewClient()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	client.Client.BaseURL = baseURL
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.IsRunnerBusy(context.Background(), "", "", "test/valid", "test1"); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+
+	if requests != 1 {
+		t.Errorf("unexpected number of requests to list runners: want 1, got %d", requests)
+	}
+
+	if err := client.RemoveRunner(context.Background(), "", "", "test/valid", int64(1)); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if _, err := client.ListRunners(context.Background(), "", "", "test/valid"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("unexpected number of requests to list runners after removing a runner: want 2, got %d", requests)
+	}
+}
+
+func TestInstallationDiscovery(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	var (
+		installationLookups int
+		tokenRequests       int
+		notFoundLookups     int
+	)
+
+	slowLookupStarted := make(chan struct{})
+	slowLookupDone := make(chan struct{})
+
+	listRunners := fake.DefaultListRunnersHandler()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/orgs/test/installation", func(w http.ResponseWriter, req *http.Request) {
+		installationLookups++
+		w.Write([]byte(`{"id": 1234}`))
+	})
+	mux.HandleFunc("/repos/test/valid/installation", func(w http.ResponseWriter, req *http.Request) {
+		installationLookups++
+		w.Write([]byte(`{"id": 1234}`))
+	})
+	mux.HandleFunc("/orgs/other/installation", func(w http.ResponseWriter, req *http.Request) {
+		notFoundLookups++
+		http.NotFound(w, req)
+	})
+	mux.HandleFunc("/orgs/slow/installation", func(w http.ResponseWriter, req *http.Request) {
+		close(slowLookupStarted)
+		<-slowLookupDone
+		http.NotFound(w, req)
+	})
+	mux.HandleFunc("/app/installations/1234/access_tokens", func(w http.ResponseWriter, req *http.Request) {
+		tokenRequests++
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "installation-token", "expires_at": "%s"}`, time.Now().Add(time.Hour).Format(time.RFC3339))
+	})
+	mux.HandleFunc("/orgs/test/actions/runners", func(w http.ResponseWriter, req *http.Request) {
+		if got := req.Header.Get("Authorization"); got != "token installation-token" {
+			t.Errorf("unexpected authorization header: %s", got)
+		}
+		listRunners.ServeHTTP(w, req)
+	})
+	mux.HandleFunc("/repos/test/valid/actions/runners", func(w http.ResponseWriter, req *http.Request) {
+		listRunners.ServeHTTP(w, req)
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	c := Config{
+		AppID:         1,
+		AppPrivateKey: string(privateKey),
+	}
+	client, err := c.NewClient()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	client.Client.BaseURL = baseURL
+
+	for i := 0; i < 2; i++ {
+		runners, err := client.ListRunners(context.Background(), "", "test", "")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(runners) != 2 {
+			t.Errorf("unexpected runners list: %v", runners)
+		}
+	}
+
+	if _, err := client.ListRunners(context.Background(), "", "", "test/valid"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if installationLookups != 1 {
+		t.Errorf("unexpected number of installation lookups: want 1, got %d", installationLookups)
+	}
+
+	if tokenRequests != 1 {
+		t.Errorf("unexpected number of installation token requests: want 1, got %d", tokenRequests)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListRunners(context.Background(), "", "other", ""); err == nil {
+			t.Errorf("expected error for the organization without the installation, got none")
+		}
+	}
+
+	if notFoundLookups != 1 {
+		t.Errorf("unexpected number of lookups of the missing installation: want 1, got %d", notFoundLookups)
+	}
+
+	// A slow lookup for an organization doesn't block the clients for the others
+	slowErr := make(chan error)
+	go func() {
+		_, err := client.ListRunners(context.Background(), "", "slow", "")
+		slowErr <- err
+	}()
+
+	<-slowLookupStarted
+
+	if _, err := client.ListRunners(context.Background(), "", "test", ""); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	close(slowLookupDone)
+
+	if err := <-slowErr; err == nil {
+		t.Errorf("expected error for the organization without the installation, got none")
+	}
+
+	if _, err := client.ListRunners(context.Background(), "test", "", ""); err == nil {
+		t.Errorf("expected error for enterprise runners, got none")
+	}
+}
+
+func TestGetRegistrationToken_SharedAndRefreshed(t *testing.T) {
+	var (
+		mu        sync.Mutex
+		requests  int
+		expiresIn = 5 * time.Minute
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != "/repos/test/valid/actions/runners/registration-token" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		mu.Lock()
+		requests++
+		token := fmt.Sprintf("token%d", requests)
+		expiresAt := time.Now().Add(expiresIn)
+		mu.Unlock()
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token": "%s", "expires_at": "%s"}`, token, expiresAt.Format(time.RFC3339))
+	}))
+	defer server.Close()
+
+	c := Config{
+		Token: "token",
+	}
+	client, err := c.NewClient()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	client.Client.BaseURL = baseURL
+
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			rt, err := client.GetRegistrationToken(ctx, "", "", "test/valid", fmt.Sprintf("runner%d", i))
+			if err != nil {
+				t.Errorf("unexpected error: %v", err)
+				return
+			}
+			if rt.GetToken() != "token1" {
+				t.Errorf("unexpected token: want token1, got %s", rt.GetToken())
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if requests != 1 {
+		t.Errorf("unexpected number of requests to create registration tokens: want 1, got %d", requests)
+	}
+
+	mu.Lock()
+	expiresIn = time.Hour
+	mu.Unlock()
+
+	// The token expiring in 5 minutes is refreshed ahead of its expiration
+	if err := client.RefreshRegistrationTokens(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if requests != 2 {
+		t.Errorf("unexpected number of requests to create registration tokens: want 2, got %d", requests)
+	}
+
+	// The refreshed token is still valid long enough
+	if err := client.RefreshRegistrationTokens(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	rt, err := client.GetRegistrationToken(ctx, "", "", "test/valid", "runner")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rt.GetToken() != "token2" {
+		t.Errorf("unexpected token: want token2, got %s", rt.GetToken())
+	}
+
+	if requests != 2 {
+		t.Errorf("unexpected number of requests to create registration tokens: want 2, got %d", requests)
+	}
+}
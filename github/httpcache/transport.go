@@ -0,0 +1,210 @@
+// Package httpcache provides conditional requests to GitHub API.
+//
+// GitHub doesn't count the requests answered with `304 Not Modified` against the rate limit,
+// so replaying the cached response on 304 lets the controller poll the API without using up the quota.
+// See https://docs.github.com/en/rest/overview/resources-in-the-rest-api#conditional-requests for details.
+package httpcache
+
+import (
+	"bytes"
+	"container/list"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	headerETag        = "ETag"
+	headerIfNoneMatch = "If-None-Match"
+
+	// DefaultMaxEntries is the default number of responses kept in the cache.
+	DefaultMaxEntries = 1000
+
+	// DefaultMaxAge is the default duration a response is kept in the cache since it was last used.
+	DefaultMaxAge = time.Hour
+)
+
+// Transport wraps a transport to send conditional requests with the ETags of the previous responses,
+// and replays the previous response when GitHub answers with 304 Not Modified.
+//
+// The cache is keyed by URL, which includes IDs of workflow runs and page numbers,
+// so the least recently used responses are evicted to bound the memory usage of a long-running controller.
+type Transport struct {
+	Transport http.RoundTripper
+
+	// MaxEntries is the maximum number of responses kept in the cache. Defaults to DefaultMaxEntries.
+	MaxEntries int
+
+	// MaxAge is how long a response is kept in the cache since it was last used. Defaults to DefaultMaxAge.
+	MaxAge time.Duration
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	// lru has the entries from the most recently used one to the least recently used one.
+	lru *list.List
+}
+
+type entry struct {
+	key      string
+	lastUsed time.Time
+
+	etag   string
+	status int
+	header http.Header
+	body   []byte
+}
+
+// RoundTrip sends a conditional request for GET requests whose response was cached before.
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet || req.Header.Get(headerIfNoneMatch) != "" {
+		return t.Transport.RoundTrip(req)
+	}
+
+	key := req.URL.String()
+
+	cached := t.get(key, time.Now())
+
+	if cached != nil {
+		// A RoundTripper must not modify the request
+		req = req.Clone(req.Context())
+		req.Header.Set(headerIfNoneMatch, cached.etag)
+	}
+
+	resp, err := t.Transport.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if resp.StatusCode == http.StatusNotModified && cached != nil {
+		resp.Body.Close()
+
+		return cached.response(req, resp.Header), nil
+	}
+
+	etag := resp.Header.Get(headerETag)
+	if resp.StatusCode != http.StatusOK || etag == "" {
+		return resp, nil
+	}
+
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+
+	t.set(&entry{
+		key:    key,
+		etag:   etag,
+		status: resp.StatusCode,
+		header: resp.Header.Clone(),
+		body:   body,
+	}, time.Now())
+
+	return resp, nil
+}
+
+func (t *Transport) get(key string, now time.Time) *entry {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	elem, ok := t.entries[key]
+	if !ok {
+		return nil
+	}
+
+	e := elem.Value.(*entry)
+
+	if now.Sub(e.lastUsed) > t.maxAge() {
+		t.remove(elem)
+
+		return nil
+	}
+
+	e.lastUsed = now
+	t.lru.MoveToFront(elem)
+
+	return e
+}
+
+func (t *Transport) set(e *entry, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.entries == nil {
+		t.entries = map[string]*list.Element{}
+		t.lru = list.New()
+	}
+
+	if elem, ok := t.entries[e.key]; ok {
+		t.remove(elem)
+	}
+
+	e.lastUsed = now
+	t.entries[e.key] = t.lru.PushFront(e)
+
+	// The least recently used entries are at the back, including the stale ones
+	for elem := t.lru.Back(); elem != nil; elem = t.lru.Back() {
+		if t.lru.Len() <= t.maxEntries() && now.Sub(elem.Value.(*entry).lastUsed) <= t.maxAge() {
+			break
+		}
+
+		t.remove(elem)
+	}
+}
+
+func (t *Transport) remove(elem *list.Element) {
+	t.lru.Remove(elem)
+	delete(t.entries, elem.Value.(*entry).key)
+}
+
+func (t *Transport) len() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	return len(t.entries)
+}
+
+func (t *Transport) maxEntries() int {
+	if t.MaxEntries > 0 {
+		return t.MaxEntries
+	}
+
+	return DefaultMaxEntries
+}
+
+func (t *Transport) maxAge() time.Duration {
+	if t.MaxAge > 0 {
+		return t.MaxAge
+	}
+
+	return DefaultMaxAge
+}
+
+// response builds the response from the cached one.
+// The rate limit headers are taken from the 304 response so that the rate limit is tracked correctly.
+func (e *entry) response(req *http.Request, notModified http.Header) *http.Response {
+	header := e.header.Clone()
+
+	for k, v := range notModified {
+		if strings.HasPrefix(http.CanonicalHeaderKey(k), "X-Ratelimit-") {
+			header[k] = v
+		}
+	}
+
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", e.status, http.StatusText(e.status)),
+		StatusCode:    e.status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(e.body)),
+		ContentLength: int64(len(e.body)),
+		Request:       req,
+	}
+}
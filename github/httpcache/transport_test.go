@@ -0,0 +1,132 @@
+package httpcache
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTransport(t *testing.T) {
+	var (
+		requests    int
+		notModified int
+		body        = `{"total_count":0,"runners":[]}`
+		etag        = `"v1"`
+	)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+
+		w.Header().Set("X-RateLimit-Remaining", "4999")
+
+		if req.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", etag)
+		w.Header().Set("X-RateLimit-Remaining", "5000")
+		w.Header().Set("Link", `<https://api.github.com/repos/test/valid/actions/runners?page=2>; rel="next"`)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: &Transport{Transport: http.DefaultTransport}}
+
+	get := func() *http.Response {
+		t.Helper()
+
+		resp, err := client.Get(server.URL + "/repos/test/valid/actions/runners")
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+
+		got, err := ioutil.ReadAll(resp.Body)
+		if err != nil {
+			t.Fatalf("%v", err)
+		}
+		resp.Body.Close()
+
+		if string(got) != body {
+			t.Errorf("unexpected body: want %s, got %s", body, string(got))
+		}
+
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("unexpected status: want %d, got %d", http.StatusOK, resp.StatusCode)
+		}
+
+		return resp
+	}
+
+	get()
+
+	resp := get()
+
+	if requests != 2 || notModified != 1 {
+		t.Errorf("unexpected requests: want 2 requests including 1 conditional request, got %d requests including %d conditional requests", requests, notModified)
+	}
+
+	if got := resp.Header.Get("X-RateLimit-Remaining"); got != "4999" {
+		t.Errorf("unexpected rate limit header: want 4999, got %s", got)
+	}
+
+	if got := resp.Header.Get("Link"); got == "" {
+		t.Errorf("missing link header of the cached response")
+	}
+
+	body = `{"total_count":1,"runners":[{"id":1}]}`
+	etag = `"v2"`
+
+	get()
+
+	if requests != 3 || notModified != 1 {
+		t.Errorf("unexpected requests after the resource changed: want 3 requests including 1 conditional request, got %d requests including %d conditional requests", requests, notModified)
+	}
+}
+
+func TestTransportBound(t *testing.T) {
+	now := time.Now()
+
+	tr := &Transport{MaxEntries: 3, MaxAge: time.Hour}
+
+	for i := 0; i < 10; i++ {
+		tr.set(&entry{key: fmt.Sprintf("page-%d", i)}, now)
+
+		if got := tr.len(); got > tr.MaxEntries {
+			t.Fatalf("cache exceeded its bound: want at most %d entries, got %d", tr.MaxEntries, got)
+		}
+	}
+
+	// page-7 is the least recently used entry unless it's used
+	if tr.get("page-7", now) == nil {
+		t.Fatalf("missing the recent entry page-7")
+	}
+
+	tr.set(&entry{key: "page-10"}, now)
+
+	if tr.get("page-8", now) != nil {
+		t.Errorf("the least recently used entry page-8 wasn't evicted")
+	}
+
+	for _, key := range []string{"page-7", "page-9", "page-10"} {
+		if tr.get(key, now) == nil {
+			t.Errorf("missing the entry %s", key)
+		}
+	}
+
+	later := now.Add(2 * time.Hour)
+
+	if tr.get("page-7", later) != nil {
+		t.Errorf("the stale entry page-7 wasn't evicted")
+	}
+
+	tr.set(&entry{key: "page-11"}, later)
+
+	if got := tr.len(); got != 1 {
+		t.Errorf("stale entries weren't evicted: want 1 entry, got %d", got)
+	}
+}
@@ -0,0 +1,144 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bradleyfalzon/ghinstallation"
+	"github.com/google/go-github/v33/github"
+)
+
+// installationNotFoundCacheDuration is how long the absence of the installation for an organization or a repository
+// is cached, so that runners of an organization the GitHub App isn't installed on don't make the lookup on every reconciliation.
+const installationNotFoundCacheDuration = time.Minute
+
+// appInstallations creates and caches the clients per installation of the GitHub App,
+// so that one App installed on many organizations works without configuring the installation ID for each of them.
+type appInstallations struct {
+	config        Config
+	appsTransport *ghinstallation.AppsTransport
+
+	mu sync.Mutex
+	// clients is keyed by the owner of the installation, i.e. the organization or the user.
+	// Each client caches its own installation token until it expires.
+	clients map[string]*Client
+	// lookups is keyed by the organization, or the repository in the owner/repo form.
+	lookups map[string]*installationLookup
+}
+
+// installationLookup serializes the lookups of the installation for an organization or a repository,
+// so that concurrent reconciliations make only one API call without blocking the ones for other installations.
+type installationLookup struct {
+	mu sync.Mutex
+	// err is the error of the last lookup that found no installation. It's returned without the API call until notFoundUntil.
+	err           error
+	notFoundUntil time.Time
+}
+
+func newAppInstallations(config Config, atr *ghinstallation.AppsTransport) *appInstallations {
+	return &appInstallations{
+		config:        config,
+		appsTransport: atr,
+		clients:       map[string]*Client{},
+		lookups:       map[string]*installationLookup{},
+	}
+}
+
+func (a *appInstallations) get(owner, key string) (*Client, *installationLookup) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	l, ok := a.lookups[key]
+	if !ok {
+		l = &installationLookup{}
+		a.lookups[key] = l
+	}
+
+	return a.clients[owner], l
+}
+
+// installationClient returns the client authenticated as the installation of the GitHub App
+// for the organization or the repository.
+func (c *Client) installationClient(ctx context.Context, enterprise, org, repo string) (*Client, error) {
+	enterprise, owner, repo, err := getEnterpriseOrganisationAndRepo(enterprise, org, repo)
+	if err != nil {
+		return nil, err
+	}
+
+	if enterprise != "" {
+		return nil, fmt.Errorf("enterprise %s: enterprise runners can't be managed with GitHub App authentication", enterprise)
+	}
+
+	key := owner
+	if repo != "" {
+		key = owner + "/" + repo
+	}
+
+	ic, l := c.apps.get(owner, key)
+	if ic != nil {
+		return ic, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// Another reconciliation may have found the installation while we were waiting for the lock
+	if ic, _ := c.apps.get(owner, key); ic != nil {
+		return ic, nil
+	}
+
+	if l.err != nil && time.Now().Before(l.notFoundUntil) {
+		return nil, l.err
+	}
+
+	var installation *github.Installation
+
+	if repo != "" {
+		installation, _, err = c.Client.Apps.FindRepositoryInstallation(ctx, owner, repo)
+	} else {
+		installation, _, err = c.Client.Apps.FindOrganizationInstallation(ctx, owner)
+	}
+	if err != nil {
+		err = fmt.Errorf("finding installation of github app for %s: %w", key, err)
+
+		var errResp *github.ErrorResponse
+		if errors.As(err, &errResp) && errResp.Response != nil && errResp.Response.StatusCode == http.StatusNotFound {
+			l.err = err
+			l.notFoundUntil = time.Now().Add(installationNotFoundCacheDuration)
+		}
+
+		return nil, err
+	}
+
+	l.err = nil
+
+	itr := ghinstallation.NewFromAppsTransport(c.apps.appsTransport, installation.GetID())
+	// Follow the API endpoint of the App client, which may have been changed after its creation
+	itr.BaseURL = strings.TrimSuffix(c.Client.BaseURL.String(), "/")
+
+	ic, err = c.apps.config.newClient(itr, strconv.FormatInt(installation.GetID(), 10))
+	if err != nil {
+		return nil, err
+	}
+
+	ic.Client.BaseURL = c.Client.BaseURL
+	ic.Client.UploadURL = c.Client.UploadURL
+
+	c.apps.mu.Lock()
+	defer c.apps.mu.Unlock()
+
+	// Lookups for the repositories of the same owner find the same installation, and share the client
+	if existing, ok := c.apps.clients[owner]; ok {
+		return existing, nil
+	}
+
+	c.apps.clients[owner] = ic
+
+	return ic, nil
+}
@@ -7,28 +7,59 @@ package metrics
 import (
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
 	"sigs.k8s.io/controller-runtime/pkg/metrics"
 )
 
 func init() {
-	metrics.Registry.MustRegister(metricRateLimit, metricRateLimitRemaining)
+	metrics.Registry.MustRegister(
+		metricRateLimit,
+		metricRateLimitRemaining,
+		metricRequests,
+		metricRequestDuration,
+	)
 }
 
+const (
+	labelInstallation = "installation"
+	labelRoute        = "route"
+	labelMethod       = "method"
+	labelStatus       = "status"
+)
+
 var (
 	// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#rate-limiting
-	metricRateLimit = prometheus.NewGauge(
+	metricRateLimit = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "github_rate_limit",
 			Help: "The maximum number of requests you're permitted to make per hour",
 		},
+		[]string{labelInstallation},
 	)
-	metricRateLimitRemaining = prometheus.NewGauge(
+	metricRateLimitRemaining = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Name: "github_rate_limit_remaining",
 			Help: "The number of requests remaining in the current rate limit window",
 		},
+		[]string{labelInstallation},
+	)
+	metricRequests = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "github_api_requests_total",
+			Help: "The number of requests made to GitHub API",
+		},
+		[]string{labelRoute, labelMethod, labelStatus},
+	)
+	metricRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "github_api_request_duration_seconds",
+			Help:    "The latency of requests made to GitHub API",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{labelRoute, labelMethod, labelStatus},
 	)
 )
 
@@ -36,28 +67,89 @@ const (
 	// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#rate-limiting
 	headerRateLimit          = "X-RateLimit-Limit"
 	headerRateLimitRemaining = "X-RateLimit-Remaining"
+
+	// statusError is the status recorded for requests that failed without any response, like on a connection error.
+	statusError = "error"
 )
 
 // Transport wraps a transport with metrics monitoring
 type Transport struct {
 	Transport http.RoundTripper
+
+	// Installation identifies the credentials the requests are made with, like the ID of the GitHub App installation.
+	// The rate limit is exported per installation, as each installation has its own rate limit.
+	Installation string
 }
 
 func (t Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+
 	resp, err := t.Transport.RoundTrip(req)
+
+	status := statusError
 	if resp != nil {
-		parseResponse(resp)
+		status = strconv.Itoa(resp.StatusCode)
+	}
+
+	labels := prometheus.Labels{
+		labelRoute:  normalizeRoute(req.URL.Path),
+		labelMethod: req.Method,
+		labelStatus: status,
+	}
+
+	metricRequests.With(labels).Inc()
+	metricRequestDuration.With(labels).Observe(time.Since(start).Seconds())
+
+	if resp != nil {
+		t.parseResponse(resp)
 	}
 	return resp, err
 }
 
-func parseResponse(resp *http.Response) {
+func (t Transport) parseResponse(resp *http.Response) {
+	labels := prometheus.Labels{labelInstallation: t.Installation}
+
 	rateLimit, err := strconv.Atoi(resp.Header.Get(headerRateLimit))
 	if err == nil {
-		metricRateLimit.Set(float64(rateLimit))
+		metricRateLimit.With(labels).Set(float64(rateLimit))
 	}
 	rateLimitRemaining, err := strconv.Atoi(resp.Header.Get(headerRateLimitRemaining))
 	if err == nil {
-		metricRateLimitRemaining.Set(float64(rateLimitRemaining))
+		metricRateLimitRemaining.With(labels).Set(float64(rateLimitRemaining))
+	}
+}
+
+// ownerSegments maps the first segment of an API path to the names of the segments following it
+// that identify the owner of the resource, so that each owner doesn't result in its own route.
+var ownerSegments = map[string][]string{
+	"repos":       {"{owner}", "{repo}"},
+	"orgs":        {"{org}"},
+	"enterprises": {"{enterprise}"},
+	"users":       {"{user}"},
+}
+
+// normalizeRoute turns the path of a GitHub API request into the route like `/repos/{owner}/{repo}/actions/runners`,
+// so that the requests can be counted per route without exploding the number of time series.
+// Numeric IDs are replaced with the placeholder named after the preceding segment, like `/runners/{runner_id}`.
+func normalizeRoute(path string) string {
+	// GitHub Enterprise Server serves the API under /api/v3
+	path = strings.TrimPrefix(path, "/api/v3")
+
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+
+	if owners, ok := ownerSegments[segments[0]]; ok {
+		for i := range owners {
+			if i+1 < len(segments) {
+				segments[i+1] = owners[i]
+			}
+		}
 	}
+
+	for i := 1; i < len(segments); i++ {
+		if _, err := strconv.ParseInt(segments[i], 10, 64); err == nil {
+			segments[i] = "{" + strings.TrimSuffix(segments[i-1], "s") + "_id}"
+		}
+	}
+
+	return "/" + strings.Join(segments, "/")
 }
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"testing"
+)
+
+func TestNormalizeRoute(t *testing.T) {
+	testcases := []struct {
+		path string
+		want string
+	}{
+		{path: "/repos/test/valid/actions/runners", want: "/repos/{owner}/{repo}/actions/runners"},
+		{path: "/repos/test/valid/actions/runners/42", want: "/repos/{owner}/{repo}/actions/runners/{runner_id}"},
+		{path: "/repos/test/valid/actions/runners/registration-token", want: "/repos/{owner}/{repo}/actions/runners/registration-token"},
+		{path: "/repos/test/valid/actions/runs/1234/jobs", want: "/repos/{owner}/{repo}/actions/runs/{run_id}/jobs"},
+		{path: "/orgs/test/actions/runners", want: "/orgs/{org}/actions/runners"},
+		{path: "/orgs/test/repos", want: "/orgs/{org}/repos"},
+		{path: "/enterprises/test/actions/runners/42", want: "/enterprises/{enterprise}/actions/runners/{runner_id}"},
+		{path: "/api/v3/orgs/test/actions/runners/registration-token", want: "/orgs/{org}/actions/runners/registration-token"},
+		{path: "/app/installations/1234/access_tokens", want: "/app/installations/{installation_id}/access_tokens"},
+		{path: "/rate_limit", want: "/rate_limit"},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.path, func(t *testing.T) {
+			if got := normalizeRoute(tc.path); got != tc.want {
+				t.Errorf("unexpected route: want %s, got %s", tc.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,128 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+)
+
+const (
+	// RegistrationTokenRefreshInterval is how often RefreshRegistrationTokens is expected to be called.
+	RegistrationTokenRefreshInterval = time.Minute
+
+	// runnerStartupTimeout is the minimum validity of a token given to a runner.
+	// We like to give runners a chance that are just starting up and may miss the expiration date by a bit.
+	runnerStartupTimeout = 3 * time.Minute
+
+	// registrationTokenRefreshBefore is how long before its expiration a token is refreshed in background,
+	// so that runners never wait for a token to be created.
+	registrationTokenRefreshBefore = 10 * time.Minute
+
+	// registrationTokenScopeIdleTimeout is how long a token is kept refreshed after it was last asked for,
+	// so that we stop creating tokens for the scopes that no longer have runners.
+	registrationTokenScopeIdleTimeout = time.Hour
+)
+
+// registrationTokenScope is the enterprise, organization or repository a registration token is created for.
+type registrationTokenScope struct {
+	enterprise, org, repo string
+
+	// lastUsed is the last time a token for the scope was asked for.
+	lastUsed time.Time
+	// mu serializes creating tokens for the scope, so that runners created at once share a single token
+	// without blocking the ones of other scopes.
+	mu *sync.Mutex
+}
+
+// RefreshRegistrationTokens creates new registration tokens for the scopes whose tokens are about to expire,
+// including the ones of the installations of the GitHub App.
+func (c *Client) RefreshRegistrationTokens(ctx context.Context) error {
+	var clients []*Client
+
+	if c.apps != nil {
+		c.apps.mu.Lock()
+		for _, ic := range c.apps.clients {
+			clients = append(clients, ic)
+		}
+		c.apps.mu.Unlock()
+	}
+
+	var errs []error
+
+	for _, ic := range clients {
+		if err := ic.RefreshRegistrationTokens(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	now := time.Now()
+
+	scopes := map[string]registrationTokenScope{}
+
+	c.mu.Lock()
+	for key, scope := range c.regScopes {
+		if now.Sub(scope.lastUsed) > registrationTokenScopeIdleTimeout {
+			delete(c.regScopes, key)
+			continue
+		}
+
+		scopes[key] = scope
+	}
+	c.mu.Unlock()
+
+	for key, scope := range scopes {
+		if _, err := c.refreshRegistrationToken(ctx, key, scope, registrationTokenRefreshBefore); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	c.cleanup()
+
+	if len(errs) > 0 {
+		return fmt.Errorf("failed to refresh %d registration tokens: %w", len(errs), errs[0])
+	}
+
+	return nil
+}
+
+// refreshRegistrationToken returns the cached token of the scope if it's valid for minValidity or longer,
+// or creates a new one.
+func (c *Client) refreshRegistrationToken(ctx context.Context, key string, scope registrationTokenScope, minValidity time.Duration) (*github.RegistrationToken, error) {
+	scope.mu.Lock()
+	defer scope.mu.Unlock()
+
+	// The token may have been created by another runner or the refresher while we were waiting for the lock
+	c.mu.Lock()
+	rt, ok := c.regTokens[key]
+	c.mu.Unlock()
+
+	if ok && rt.GetExpiresAt().After(time.Now().Add(minValidity)) {
+		return rt, nil
+	}
+
+	enterprise, owner, repo, err := getEnterpriseOrganisationAndRepo(scope.enterprise, scope.org, scope.repo)
+	if err != nil {
+		return nil, err
+	}
+
+	rt, res, err := c.createRegistrationToken(ctx, enterprise, owner, repo)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create registration token: %w", err)
+	}
+
+	if res.StatusCode != 201 {
+		return nil, fmt.Errorf("unexpected status: %d", res.StatusCode)
+	}
+
+	c.mu.Lock()
+	c.regTokens[key] = rt
+	c.mu.Unlock()
+
+	// A runner is likely to register itself with the new token soon
+	c.runners.invalidate(key)
+
+	return rt, nil
+}
@@ -0,0 +1,78 @@
+package github
+
+import (
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+)
+
+// runnerListCache caches the runners listed per enterprise, organization or repository,
+// so that checking the status of each runner of a large pool doesn't end up listing all the runners every time.
+type runnerListCache struct {
+	duration time.Duration
+
+	mu      sync.Mutex
+	entries map[string]runnerListCacheEntry
+}
+
+type runnerListCacheEntry struct {
+	runners   []*github.Runner
+	expiresAt time.Time
+}
+
+func newRunnerListCache(duration time.Duration) *runnerListCache {
+	return &runnerListCache{
+		duration: duration,
+		entries:  map[string]runnerListCacheEntry{},
+	}
+}
+
+// get returns the cached runners of the scope, or false if there's none or it's expired.
+func (c *runnerListCache) get(key string, now time.Time) ([]*github.Runner, bool) {
+	if c == nil || c.duration <= 0 {
+		return nil, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	if !now.Before(e.expiresAt) {
+		delete(c.entries, key)
+
+		return nil, false
+	}
+
+	return append([]*github.Runner{}, e.runners...), true
+}
+
+func (c *runnerListCache) set(key string, runners []*github.Runner, now time.Time) {
+	if c == nil || c.duration <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key] = runnerListCacheEntry{
+		runners:   append([]*github.Runner{}, runners...),
+		expiresAt: now.Add(c.duration),
+	}
+}
+
+// invalidate drops the cached runners of the scope, so that the next listing reflects a runner added to or removed from it.
+func (c *runnerListCache) invalidate(key string) {
+	if c == nil {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.entries, key)
+}
@@ -0,0 +1,165 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+)
+
+const (
+	// https://docs.github.com/en/rest/overview/resources-in-the-rest-api#rate-limiting
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+	headerRetryAfter         = "Retry-After"
+
+	// defaultAbuseRetryAfter is how long the client pauses on a secondary rate limit without Retry-After.
+	defaultAbuseRetryAfter = time.Minute
+)
+
+// RateLimitedError is returned instead of calling GitHub API while the client is paused by a rate limit,
+// or the call isn't essential and the remaining quota is kept for the essential ones.
+type RateLimitedError struct {
+	// RetryAt is the time the rate limit resets, at which the call is expected to succeed.
+	RetryAt time.Time
+	Reason  string
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("github api calls are paused until %s: %s", e.RetryAt.Format(time.RFC3339), e.Reason)
+}
+
+// RetryAt returns the time at which the GitHub API call that failed with err is expected to succeed,
+// when err is caused by a rate limit.
+func RetryAt(err error) (time.Time, bool) {
+	var limited *RateLimitedError
+	if errors.As(err, &limited) {
+		return limited.RetryAt, true
+	}
+
+	var rateLimit *github.RateLimitError
+	if errors.As(err, &rateLimit) {
+		return rateLimit.Rate.Reset.Time, true
+	}
+
+	var abuse *github.AbuseRateLimitError
+	if errors.As(err, &abuse) {
+		return time.Now().Add(abuse.GetRetryAfter()), true
+	}
+
+	return time.Time{}, false
+}
+
+// essentialRequests are the API calls that are needed to keep runners working,
+// and are made even when the remaining quota is at or below the reserve.
+var essentialRequests = []struct {
+	method string
+	path   *regexp.Regexp
+}{
+	{method: http.MethodPost, path: regexp.MustCompile(`/actions/runners/registration-token$`)},
+	{method: http.MethodDelete, path: regexp.MustCompile(`/actions/runners/[0-9]+$`)},
+}
+
+// isConditionalRequest returns true for the requests with the ETag of a previous response.
+// They are made even when the remaining quota is at or below the reserve,
+// as GitHub doesn't count them against the rate limit when answered with 304 Not Modified.
+func isConditionalRequest(req *http.Request) bool {
+	return req.Header.Get("If-None-Match") != ""
+}
+
+func isEssentialRequest(req *http.Request) bool {
+	for _, e := range essentialRequests {
+		if req.Method == e.method && e.path.MatchString(req.URL.Path) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// throttleTransport pauses GitHub API calls made by a client while its rate limit is exhausted,
+// so that reconcilers don't burn the quota or get the credentials blocked by retrying too early.
+type throttleTransport struct {
+	Transport http.RoundTripper
+
+	// reserve is the number of remaining requests in the current window kept for essential requests.
+	reserve int
+
+	mu sync.Mutex
+	// pausedUntil is the time until which all the requests are refused, set on a rate limit error.
+	pausedUntil time.Time
+	pausedFor   string
+	remaining   int
+	reset       time.Time
+}
+
+func (t *throttleTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.check(req, time.Now()); err != nil {
+		return nil, err
+	}
+
+	resp, err := t.Transport.RoundTrip(req)
+	if resp != nil {
+		t.observe(resp, time.Now())
+	}
+
+	return resp, err
+}
+
+func (t *throttleTransport) check(req *http.Request, now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if now.Before(t.pausedUntil) {
+		return &RateLimitedError{RetryAt: t.pausedUntil, Reason: t.pausedFor}
+	}
+
+	if t.reserve > 0 && now.Before(t.reset) && t.remaining <= t.reserve && !isEssentialRequest(req) && !isConditionalRequest(req) {
+		return &RateLimitedError{
+			RetryAt: t.reset,
+			Reason:  fmt.Sprintf("the remaining %d requests are reserved for registering and removing runners", t.remaining),
+		}
+	}
+
+	return nil
+}
+
+func (t *throttleTransport) observe(resp *http.Response, now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	remaining, remainingErr := strconv.Atoi(resp.Header.Get(headerRateLimitRemaining))
+	reset, resetErr := strconv.ParseInt(resp.Header.Get(headerRateLimitReset), 10, 64)
+
+	if remainingErr == nil && resetErr == nil {
+		t.remaining = remaining
+		t.reset = time.Unix(reset, 0)
+	}
+
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return
+	}
+
+	if v := resp.Header.Get(headerRetryAfter); v != "" {
+		retryAfter := defaultAbuseRetryAfter
+		if seconds, err := strconv.Atoi(v); err == nil {
+			retryAfter = time.Duration(seconds) * time.Second
+		}
+
+		t.pause(now.Add(retryAfter), "secondary rate limit exceeded")
+	} else if remainingErr == nil && resetErr == nil && remaining == 0 {
+		t.pause(t.reset, "rate limit exceeded")
+	}
+}
+
+func (t *throttleTransport) pause(until time.Time, reason string) {
+	if until.After(t.pausedUntil) {
+		t.pausedUntil = until
+		t.pausedFor = reason
+	}
+}
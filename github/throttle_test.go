@@ -0,0 +1,196 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/summerwind/actions-runner-controller/github/fake"
+)
+
+func TestThrottle(t *testing.T) {
+	var (
+		requests   int
+		remaining  = 5000
+		retryAfter string
+	)
+
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	listRunners := fake.DefaultListRunnersHandler()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+
+		if retryAfter != "" {
+			w.Header().Set("Retry-After", retryAfter)
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"You have exceeded a secondary rate limit"}`))
+			return
+		}
+
+		if req.URL.Path == "/repos/test/valid/actions/runners/1" {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		listRunners.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+
+	c := Config{
+		Token:            "token",
+		RateLimitReserve: 10,
+	}
+	client, err := c.NewClient()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	client.Client.BaseURL = baseURL
+
+	ctx := context.Background()
+
+	if _, err := client.ListRunners(ctx, "", "", "test/valid"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	remaining = 10
+
+	// Consumes the remaining requests down to the reserve
+	if _, err := client.ListRunners(ctx, "", "", "test/valid"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err = client.ListRunners(ctx, "", "", "test/valid")
+	var limited *RateLimitedError
+	if !errors.As(err, &limited) {
+		t.Fatalf("expected RateLimitedError for a non-essential call within the reserve, got %v", err)
+	}
+	if retryAt, ok := RetryAt(err); !ok || !retryAt.Equal(reset) {
+		t.Errorf("unexpected retry time: want %s, got %s", reset, retryAt)
+	}
+	if requests != 2 {
+		t.Errorf("unexpected number of requests: want 2, got %d", requests)
+	}
+
+	if err := client.RemoveRunner(ctx, "", "", "test/valid", int64(1)); err != nil {
+		t.Fatalf("expected an essential call to be made within the reserve, got %v", err)
+	}
+	if requests != 3 {
+		t.Errorf("unexpected number of requests: want 3, got %d", requests)
+	}
+
+	retryAfter = "60"
+
+	if err := client.RemoveRunner(ctx, "", "", "test/valid", int64(1)); err == nil {
+		t.Fatalf("expected error for the secondary rate limit, got none")
+	}
+
+	before := time.Now()
+
+	err = client.RemoveRunner(ctx, "", "", "test/valid", int64(1))
+	if !errors.As(err, &limited) {
+		t.Fatalf("expected RateLimitedError for an essential call while paused, got %v", err)
+	}
+	if retryAt, ok := RetryAt(err); !ok || retryAt.Before(before.Add(50*time.Second)) || retryAt.After(before.Add(60*time.Second)) {
+		t.Errorf("unexpected retry time: want about 60s after %s, got %s", before, retryAt)
+	}
+	if requests != 4 {
+		t.Errorf("unexpected number of requests: want 4, got %d", requests)
+	}
+}
+
+func TestRetryAt(t *testing.T) {
+	reset := time.Now().Add(time.Hour)
+
+	if _, ok := RetryAt(errors.New("unexpected error")); ok {
+		t.Errorf("expected no retry time for an error other than rate limits")
+	}
+
+	rateLimit := &github.RateLimitError{Rate: github.Rate{Reset: github.Timestamp{Time: reset}}}
+	if got, ok := RetryAt(fmt.Errorf("failed to list runners: %w", rateLimit)); !ok || !got.Equal(reset) {
+		t.Errorf("unexpected retry time for RateLimitError: want %s, got %s", reset, got)
+	}
+
+	retryAfter := 30 * time.Second
+	before := time.Now()
+	abuse := &github.AbuseRateLimitError{RetryAfter: &retryAfter}
+	if got, ok := RetryAt(fmt.Errorf("failed to list runners: %w", abuse)); !ok || got.Before(before.Add(retryAfter)) {
+		t.Errorf("unexpected retry time for AbuseRateLimitError: want after %s, got %s", before.Add(retryAfter), got)
+	}
+}
+
+func TestThrottle_ConditionalRequests(t *testing.T) {
+	var requests, notModified int
+
+	reset := time.Now().Add(time.Hour).Truncate(time.Second)
+
+	listRunners := fake.DefaultListRunnersHandler()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		requests++
+
+		w.Header().Set("X-RateLimit-Remaining", "10")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(reset.Unix(), 10))
+		w.Header().Set("ETag", `"runners"`)
+
+		if req.Header.Get("If-None-Match") == `"runners"` {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		listRunners.ServeHTTP(w, req)
+	}))
+	defer server.Close()
+
+	c := Config{
+		Token:               "token",
+		RateLimitReserve:    10,
+		ConditionalRequests: true,
+	}
+	client, err := c.NewClient()
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatalf("%v", err)
+	}
+	client.Client.BaseURL = baseURL
+
+	ctx := context.Background()
+
+	// Consumes the remaining requests down to the reserve
+	want, err := client.ListRunners(ctx, "", "", "test/valid")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got, err := client.ListRunners(ctx, "", "", "test/valid")
+	if err != nil {
+		t.Fatalf("expected a conditional request to be made within the reserve, got %v", err)
+	}
+	if len(got) != len(want) {
+		t.Errorf("unexpected number of runners from the cached response: want %d, got %d", len(want), len(got))
+	}
+	if requests != 2 || notModified != 1 {
+		t.Errorf("unexpected number of requests: want 2 including 1 conditional request, got %d including %d", requests, notModified)
+	}
+}
@@ -81,8 +81,11 @@ func main() {
 	flag.StringVar(&dockerImage, "docker-image", defaultDockerImage, "The image name of docker sidecar container.")
 	flag.StringVar(&c.Token, "github-token", c.Token, "The personal access token of GitHub.")
 	flag.Int64Var(&c.AppID, "github-app-id", c.AppID, "The application ID of GitHub App.")
-	flag.Int64Var(&c.AppInstallationID, "github-app-installation-id", c.AppInstallationID, "The installation ID of GitHub App.")
+	flag.Int64Var(&c.AppInstallationID, "github-app-installation-id", c.AppInstallationID, "The installation ID of GitHub App. Omit it to look up the installation for the organization or repository of each runner.")
 	flag.StringVar(&c.AppPrivateKey, "github-app-private-key", c.AppPrivateKey, "The path of a private key file to authenticate as a GitHub App")
+	flag.DurationVar(&c.RunnerListCacheDuration, "github-runner-list-cache-duration", c.RunnerListCacheDuration, "Determines how long the runners listed per enterprise, organization or repository are reused to check the status of each runner. Set to 0 to list the runners every time")
+	flag.BoolVar(&c.ConditionalRequests, "github-conditional-requests", c.ConditionalRequests, "Send GitHub API requests with the ETags of the previous responses, so that the responses that haven't changed don't count against the rate limit. The previous responses are kept in memory")
+	flag.IntVar(&c.RateLimitReserve, "github-rate-limit-reserve", c.RateLimitReserve, "The number of remaining GitHub API requests in the rate limit window kept for creating registration tokens and removing runners. The other API calls are delayed until the rate limit resets once the remaining requests drop to this number. Set to 0 to disable")
 	flag.DurationVar(&syncPeriod, "sync-period", 10*time.Minute, "Determines the minimum frequency at which K8s resources managed by this controller are reconciled. When you use autoscaling, set to a lower value like 10 minute, because this corresponds to the minimum time to react on demand change")
 	flag.Var(&commonRunnerLabels, "common-runner-labels", "Runner labels in the K1=V1,K2=V2,... format that are inherited all the runners created by the controller. See https://github.com/summerwind/actions-runner-controller/issues/321 for more information")
 	flag.StringVar(&namespace, "watch-namespace", "", "The namespace to watch for custom resources. Set to empty for letting it watch for all namespaces.")
@@ -113,11 +116,20 @@ func main() {
 		os.Exit(1)
 	}
 
+	// multiClient is shared by the reconcilers so that each set of GitHub API credentials ends up with a single client,
+	// sharing its caches and rate limit.
+	multiClient := controllers.NewMultiGitHubClient(mgr.GetClient(), ghClient, c)
+
+	if err = mgr.Add(multiClient); err != nil {
+		setupLog.Error(err, "unable to add the registration token refresher")
+		os.Exit(1)
+	}
+
 	runnerReconciler := &controllers.RunnerReconciler{
 		Client:       mgr.GetClient(),
 		Log:          ctrl.Log.WithName("controllers").WithName("Runner"),
 		Scheme:       mgr.GetScheme(),
-		GitHubClient: ghClient,
+		GitHubClient: multiClient,
 		RunnerImage:  runnerImage,
 		DockerImage:  dockerImage,
 	}
@@ -131,7 +143,7 @@ func main() {
 		Client:       mgr.GetClient(),
 		Log:          ctrl.Log.WithName("controllers").WithName("RunnerReplicaSet"),
 		Scheme:       mgr.GetScheme(),
-		GitHubClient: ghClient,
+		GitHubClient: multiClient,
 	}
 
 	if err = runnerSetReconciler.SetupWithManager(mgr); err != nil {
@@ -152,11 +164,12 @@ func main() {
 	}
 
 	horizontalRunnerAutoscaler := &controllers.HorizontalRunnerAutoscalerReconciler{
-		Client:        mgr.GetClient(),
-		Log:           ctrl.Log.WithName("controllers").WithName("HorizontalRunnerAutoscaler"),
-		Scheme:        mgr.GetScheme(),
-		GitHubClient:  ghClient,
-		CacheDuration: syncPeriod - 10*time.Second,
+		Client:             mgr.GetClient(),
+		Log:                ctrl.Log.WithName("controllers").WithName("HorizontalRunnerAutoscaler"),
+		Scheme:             mgr.GetScheme(),
+		GitHubClient:       multiClient,
+		CacheDuration:      syncPeriod - 10*time.Second,
+		CommonRunnerLabels: commonRunnerLabels,
 	}
 
 	if err = horizontalRunnerAutoscaler.SetupWithManager(mgr); err != nil {
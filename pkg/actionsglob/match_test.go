@@ -68,6 +68,46 @@ func TestMatch(t *testing.T) {
 		})
 	})
 
+	t.Run("*foo != bar", func(t *testing.T) {
+		run(t, testcase{
+			Pattern: "*foo",
+			Target:  "bar",
+			Want:    false,
+		})
+	})
+
+	t.Run("*-api != web", func(t *testing.T) {
+		run(t, testcase{
+			Pattern: "*-api",
+			Target:  "web",
+			Want:    false,
+		})
+	})
+
+	t.Run("!*-sandbox == prod", func(t *testing.T) {
+		run(t, testcase{
+			Pattern: "!*-sandbox",
+			Target:  "prod",
+			Want:    true,
+		})
+	})
+
+	t.Run("foo* != bar", func(t *testing.T) {
+		run(t, testcase{
+			Pattern: "foo*",
+			Target:  "bar",
+			Want:    false,
+		})
+	})
+
+	t.Run("foo != empty", func(t *testing.T) {
+		run(t, testcase{
+			Pattern: "foo",
+			Target:  "",
+			Want:    false,
+		})
+	})
+
 	t.Run("*foo == 1foo", func(t *testing.T) {
 		run(t, testcase{
 			Pattern: "*foo",
@@ -37,35 +37,50 @@ if [ -n "${RUNNER_LABELS}" ]; then
   LABEL_ARG="--labels ${RUNNER_LABELS}"
 fi
 
-if [ -z "${RUNNER_TOKEN}" ]; then
-  echo "RUNNER_TOKEN must be set" 1>&2
-  exit 1
-fi
-
 if [ -z "${RUNNER_REPO}" ] && [ -n "${RUNNER_GROUP}" ];then
   RUNNER_GROUP_ARG="--runnergroup ${RUNNER_GROUP}"
 fi
 
+if [ "${RUNNER_EPHEMERAL}" == "true" ]; then
+  EPHEMERAL_ARG="--ephemeral"
+fi
+
 # Hack due to https://github.com/summerwind/actions-runner-controller/issues/252#issuecomment-758338483
 if [ ! -d /runner ]; then
   echo "/runner should be an emptyDir mount. Please fix the pod spec." 1>&2
   exit 1
 fi
 
-sudo chown -R runner:docker /runner
-mv /runnertmp/* /runner/
+# The runner container restarted in place finds the runner already configured in the /runner emptyDir.
+# The registration token secret is deleted once the runner is registered, so RUNNER_TOKEN is empty in that case.
+if [ -f /runner/.runner ]; then
+  echo "Runner is already configured. Skipping configuration" 1>&2
+  cd /runner
+else
+  if [ -z "${RUNNER_TOKEN}" ]; then
+    echo "RUNNER_TOKEN must be set" 1>&2
+    exit 1
+  fi
+
+  sudo chown -R runner:docker /runner
+  mv /runnertmp/* /runner/
 
-cd /runner
-./config.sh --unattended --replace --name "${RUNNER_NAME}" --url "${GITHUB_URL}${ATTACH}" --token "${RUNNER_TOKEN}" ${RUNNER_GROUP_ARG} ${LABEL_ARG} ${WORKDIR_ARG}
-mkdir ./externals
-# Hack due to the DinD volumes
-mv ./externalstmp/* ./externals/
+  cd /runner
+  if ! ./config.sh --unattended --replace --name "${RUNNER_NAME}" --url "${GITHUB_URL}${ATTACH}" --token "${RUNNER_TOKEN}" ${RUNNER_GROUP_ARG} ${LABEL_ARG} ${WORKDIR_ARG} ${EPHEMERAL_ARG}; then
+    echo "Failed to configure the runner" 1>&2
+    # EX_CONFIG, which the controller recognizes as a failure of config.sh
+    exit 78
+  fi
+  mkdir ./externals
+  # Hack due to the DinD volumes
+  mv ./externalstmp/* ./externals/
 
-for f in runsvc.sh RunnerService.js; do
-  diff {bin,patched}/${f} || :
-  sudo mv bin/${f}{,.bak}
-  sudo mv {patched,bin}/${f}
-done
+  for f in runsvc.sh RunnerService.js; do
+    diff {bin,patched}/${f} || :
+    sudo mv bin/${f}{,.bak}
+    sudo mv {patched,bin}/${f}
+  done
+fi
 
 unset RUNNER_NAME RUNNER_REPO RUNNER_TOKEN
 exec ./bin/runsvc.sh --once